@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/scheduler"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func (app *application) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	type jobStatus struct {
+		scheduler.Job
+		LastRun *data.JobRun `json:"last_run"`
+	}
+
+	var jobs []jobStatus
+
+	for _, job := range app.scheduler.Jobs() {
+		runs, err := app.models.JobRuns.List(r.Context(), job.Name, 1)
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		status := jobStatus{Job: job}
+
+		if len(runs) > 0 {
+			status.LastRun = &runs[0]
+		}
+
+		jobs = append(jobs, status)
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelop{"jobs": jobs})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listJobRunsHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "job_name")
+
+	limit := 20
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+
+		if err != nil || parsed < 1 || parsed > 100 {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		limit = parsed
+	}
+
+	runs, err := app.models.JobRuns.List(r.Context(), name, limit)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"runs": runs})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) triggerJobHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "job_name")
+
+	// The run must not be cut short if the caller hangs up.
+	run, err := app.scheduler.Trigger(context.WithoutCancel(r.Context()), name)
+
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		app.NotFoundError(w, r)
+		return
+	}
+
+	if errors.Is(err, scheduler.ErrJobRunning) {
+		app.WriteErrorCode(w, r, http.StatusConflict, CodeJobRunning, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.InternalServerError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"run": run})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
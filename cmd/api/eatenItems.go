@@ -2,7 +2,6 @@ package main
 
 import (
 	"github.com/Piccio-Code/MealStore/internal/data"
-	"github.com/go-playground/validator/v10"
 	"net/http"
 )
 
@@ -16,7 +15,7 @@ func (app *application) createEatenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newEatenItem)
 
 	if err != nil {
@@ -38,27 +37,34 @@ func (app *application) createEatenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// The item comes from the path on the by-name routes.
+	if itemId, ok := r.Context().Value(ItemIdKey).(int); ok {
+		newEatenItem.ItemId = itemId
+	}
+
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	_, err = app.models.Items.Get(r.Context(), newEatenItem.ItemId, storeId)
 
 	if err != nil {
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	err = app.models.EatenItems.Create(r.Context(), newEatenItem)
 
 	if err != nil {
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
+	app.emit(r.Context(), userId, storeId, data.EventEatenCreated, newEatenItem)
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -75,7 +81,7 @@ func (app *application) createEatenListHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newEatenItemList)
 
 	if err != nil {
@@ -104,8 +110,7 @@ func (app *application) createEatenListHandler(w http.ResponseWriter, r *http.Re
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -113,8 +118,7 @@ func (app *application) createEatenListHandler(w http.ResponseWriter, r *http.Re
 		_, err := app.models.Items.Get(r.Context(), item.ItemId, storeId)
 
 		if err != nil {
-			app.errorLog.Println(err)
-			app.BadRequestError(w, r)
+			app.DBError(w, r, err)
 			return
 		}
 
@@ -123,11 +127,14 @@ func (app *application) createEatenListHandler(w http.ResponseWriter, r *http.Re
 	err = app.models.EatenItems.CreateList(r.Context(), newEatenItemList.NewEatenItems)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
+	for _, item := range newEatenItemList.NewEatenItems {
+		app.emit(r.Context(), userId, storeId, data.EventEatenCreated, item)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -156,16 +163,14 @@ func (app *application) getEatenHandler(w http.ResponseWriter, r *http.Request)
 	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	_, err = app.models.Items.Get(r.Context(), itemId, storeId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -173,7 +178,7 @@ func (app *application) getEatenHandler(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.ValidationError(w, r, err)
 		return
 	}
 
@@ -182,8 +187,7 @@ func (app *application) getEatenHandler(w http.ResponseWriter, r *http.Request)
 	items, err := app.models.EatenItems.Get(r.Context(), itemId, filters)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
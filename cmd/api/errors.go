@@ -3,18 +3,101 @@ package main
 import (
 	"errors"
 	"fmt"
+	"github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"net/http"
+	"regexp"
+	"strings"
 )
 
-func (app *application) WriteError(w http.ResponseWriter, r *http.Request, status int, message string) {
-	err := app.writeJSON(w, status, envelop{"error": message})
+// Error codes are part of the API: clients match on them, so they never
+// change once published. Messages are for humans and may.
+const (
+	CodeBadRequest            = "bad_request"
+	CodeValidationFailed      = "validation_failed"
+	CodeUnauthorized          = "unauthorized"
+	CodeNotFound              = "not_found"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeConflict              = "conflict"
+	CodeDuplicate             = "duplicate"
+	CodeEditConflict          = "edit_conflict"
+	CodeForeignKey            = "foreign_key_violation"
+	CodePreconditionFailed    = "precondition_failed"
+	CodeVersionRequired       = "version_required"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+	CodeIdempotencyInProgress = "idempotency_key_in_progress"
+	CodeInternal              = "internal_error"
+	CodeUnknownItem           = "unknown_item"
+	CodeUnknownIngredient     = "unknown_ingredient"
+	CodeEntryChecked          = "entry_already_checked"
+	CodeNothingLow            = "nothing_low"
+	CodePresetItemsMissing    = "preset_items_missing"
+	CodeJobRunning            = "job_running"
+	CodeNotEnoughStock        = "not_enough_stock"
+	CodeUnsupportedDocument   = "unsupported_document"
+	CodeItemInUse             = "item_in_use"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	http.StatusConflict:            CodeConflict,
+	http.StatusPreconditionFailed:  CodePreconditionFailed,
+	http.StatusUnprocessableEntity: CodeValidationFailed,
+}
+
+// ErrorResponse is the body of every error, under the "error" key.
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+
+	// Conflict is the record in the way of a duplicate name.
+	Conflict *data.DuplicateNameError `json:"conflict,omitempty"`
+
+	// References are what keeps an item from leaving its store.
+	References []data.ItemReference `json:"references,omitempty"`
+}
+
+// FieldError explains why a single field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (app *application) writeError(w http.ResponseWriter, r *http.Request, status int, body ErrorResponse) {
+	body.RequestId = middleware.GetReqID(r.Context())
+
+	err := app.writeJSON(w, status, envelop{"error": body})
+
 	if err != nil {
 		app.errorLog.Println(err)
-		app.InternalServerError(w, r)
+		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
+// WriteErrorCode answers with an error that clients can tell apart from
+// others with the same status.
+func (app *application) WriteErrorCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	app.writeError(w, r, status, ErrorResponse{Code: code, Message: message})
+}
+
+func (app *application) WriteError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	code, ok := statusCodes[status]
+
+	if !ok {
+		code = CodeInternal
+	}
+
+	app.WriteErrorCode(w, r, status, code, message)
+}
+
 func (app *application) NotFoundError(w http.ResponseWriter, r *http.Request) {
 	app.WriteError(w, r, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 }
@@ -35,16 +118,107 @@ func (app *application) UnauthorizedError(w http.ResponseWriter, r *http.Request
 	app.WriteError(w, r, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 }
 
-func (app *application) ValidationError(w http.ResponseWriter, r *http.Request, err error) {
+// DBError answers with the status that matches an error from the models:
+// 404 for a missing record, 409 for a duplicate or a stale version and 422
+// for a reference to a record that does not exist. Anything else is logged
+// and reported as an internal error.
+func (app *application) DBError(w http.ResponseWriter, r *http.Request, err error) {
+	status, body := app.dbErrorResponse(err)
+
+	app.writeError(w, r, status, body)
+}
+
+func (app *application) dbErrorResponse(err error) (int, ErrorResponse) {
+	var clash *data.DuplicateNameError
+	var inUse *data.ItemInUseError
+
+	switch {
+	case errors.As(err, &clash):
+		return http.StatusConflict, ErrorResponse{Code: CodeDuplicate, Message: err.Error(), Conflict: clash}
+	case errors.As(err, &inUse):
+		return http.StatusConflict, ErrorResponse{Code: CodeItemInUse, Message: err.Error(), References: inUse.References}
+	case errors.Is(err, data.ErrNotFound):
+		return http.StatusNotFound, ErrorResponse{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, data.ErrDuplicate):
+		return http.StatusConflict, ErrorResponse{Code: CodeDuplicate, Message: err.Error()}
+	case errors.Is(err, data.ErrEditConflict):
+		return http.StatusConflict, ErrorResponse{Code: CodeEditConflict, Message: err.Error()}
+	case errors.Is(err, data.ErrForeignKey):
+		return http.StatusUnprocessableEntity, ErrorResponse{Code: CodeForeignKey, Message: err.Error()}
+	}
+
+	app.errorLog.Println(err)
+
+	return http.StatusInternalServerError, ErrorResponse{Code: CodeInternal, Message: http.StatusText(http.StatusInternalServerError)}
+}
 
+// ValidationError answers once with every field that failed validation.
+// Errors that don't come from the validator are plain bad requests.
+func (app *application) ValidationError(w http.ResponseWriter, r *http.Request, err error) {
 	var validateErrs validator.ValidationErrors
 
-	if errors.As(err, &validateErrs) {
-		for _, e := range validateErrs {
-			message := fmt.Sprintf("the field %v must met: tag: %v, value: %v", e.StructField(), e.ActualTag(), e.Param())
+	if !errors.As(err, &validateErrs) {
+		app.errorLog.Println(err)
+		app.WriteError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	details := make([]FieldError, 0, len(validateErrs))
 
-			app.errorLog.Println(message)
-			app.WriteError(w, r, http.StatusBadRequest, message)
-		}
+	for _, e := range validateErrs {
+		details = append(details, FieldError{
+			Field:   fieldPath(e.Namespace()),
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: ruleMessage(e.Tag(), e.Param()),
+		})
 	}
+
+	app.writeError(w, r, http.StatusBadRequest, ErrorResponse{
+		Code:    CodeValidationFailed,
+		Message: fmt.Sprintf("%d field(s) failed validation", len(details)),
+		Details: details,
+	})
+}
+
+var upperRun = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// fieldPath turns the validator namespace "Preset.entries[0].item_id" into
+// the JSON path "entries[0].item_id". Fields are named by their json tag,
+// see newValidator; a field without one keeps its Go name, snake cased.
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		namespace = rest
+	}
+
+	return strings.ToLower(upperRun.ReplaceAllString(namespace, "${1}_${2}"))
+}
+
+func ruleMessage(rule, param string) string {
+	switch rule {
+	case "required", "required_without", "required_with":
+		return "is required"
+	case "gte", "min":
+		return fmt.Sprintf("must be at least %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "lte", "max":
+		return fmt.Sprintf("must be at most %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(param, " ", ", "))
+	case "datetime":
+		return fmt.Sprintf("must be a date in the format %s", param)
+	case "http_url", "url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	}
+
+	if param != "" {
+		return fmt.Sprintf("must satisfy %s=%s", rule, param)
+	}
+
+	return fmt.Sprintf("must satisfy %s", rule)
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidationErrorFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"empty list", `{"items": []}`, "items"},
+		{"capacity below one", `{"items": [{"id": 1, "current_capacity": 0, "version": "v"}]}`, "items[0].current_capacity"},
+		{"missing id", `{"items": [{"id": 1, "version": "v"}, {"name": "Latte", "version": "v"}]}`, "items[1].id"},
+	}
+
+	app := &application{infoLog: log.New(io.Discard, "", 0), errorLog: log.New(io.Discard, "", 0)}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req updateItemsListRequest
+
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			app.ValidationError(w, httptest.NewRequest(http.MethodPatch, "/", nil), newValidator().Struct(req))
+
+			var resp struct {
+				Error ErrorResponse `json:"error"`
+			}
+
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp.Error.Details) != 1 || resp.Error.Details[0].Field != tt.field {
+				t.Errorf("got details %+v, want one for %s", resp.Error.Details, tt.field)
+			}
+		})
+	}
+}
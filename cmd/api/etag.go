@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"github.com/Piccio-Code/MealStore/internal/data"
+	"net/http"
+	"strings"
+)
+
+// etag quotes a record version as a strong entity tag.
+func etag(version *string) string {
+	if version == nil {
+		return ""
+	}
+
+	return `"` + *version + `"`
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header value
+// lists version. Weak tags compare by their value and "*" matches any
+// version.
+func etagMatches(header string, version *string) bool {
+	if version == nil {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			return true
+		}
+
+		tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+
+		if tag == *version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified sets the ETag of a record about to be sent. When If-None-Match
+// says the client already has that version it answers 304 and returns true.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, version *string) bool {
+	if version != nil {
+		w.Header().Set("ETag", etag(version))
+	}
+
+	header := r.Header.Get("If-None-Match")
+
+	if header == "" || !etagMatches(header, version) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// hasIfMatch reports whether the request is guarded by If-Match.
+func hasIfMatch(r *http.Request) bool {
+	return r.Header.Get("If-Match") != ""
+}
+
+// preconditionFailed checks If-Match against the current version of a
+// record. When it doesn't match it answers 412 and returns true.
+func (app *application) preconditionFailed(w http.ResponseWriter, r *http.Request, version *string) bool {
+	if !hasIfMatch(r) || etagMatches(r.Header.Get("If-Match"), version) {
+		return false
+	}
+
+	app.WriteErrorCode(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "the record does not match If-Match, fetch it again")
+
+	return true
+}
+
+// conditionalError is DBError for a write guarded by If-Match, where a
+// version that changed in the meantime is a failed precondition.
+func (app *application) conditionalError(w http.ResponseWriter, r *http.Request, err error) {
+	if hasIfMatch(r) && errors.Is(err, data.ErrEditConflict) {
+		app.WriteErrorCode(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+		return
+	}
+
+	app.DBError(w, r, err)
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
 type OptionStruct struct {
@@ -22,6 +26,23 @@ func (app *application) getIdParam(r *http.Request, name string) (int, error) {
 	return id, nil
 }
 
+// getNameParam returns a name from the path. chi routes on the escaped
+// path when it holds an encoded slash, and then the name needs decoding.
+func (app *application) getNameParam(r *http.Request, name string) (string, error) {
+	value := chi.URLParam(r, name)
+	var err error
+
+	if r.URL.RawPath != "" {
+		value, err = url.PathUnescape(value)
+	}
+
+	if err != nil || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("error getting the name: {%s}", name)
+	}
+
+	return value, nil
+}
+
 type envelop map[string]interface{}
 
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelop) error {
@@ -46,3 +67,21 @@ func (app *application) readeJSON(r *http.Request, dst interface{}) error {
 
 	return dec.Decode(&dst)
 }
+
+// newValidator returns a validator that names fields by their json tag, so
+// that validation errors point at the fields of the request body.
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	return v
+}
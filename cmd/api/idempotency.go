@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/go-chi/chi/v5/middleware"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyKeyTTL    = 24 * time.Hour
+	idempotencyKeyMaxLen = 255
+	idempotencyMaxBody   = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with the response. The
+// others, like the request id, belong to the retry itself.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency makes POST requests sent with an Idempotency-Key header safe
+// to retry. The first response is stored and replayed to every retry with
+// the same key, with its Content-Type, Location and ETag headers; a retry
+// with another payload is refused. Responses with a 5xx status are not
+// stored so that the request can be tried again.
+func (app *application) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > idempotencyKeyMaxLen {
+			app.WriteError(w, r, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+		if !ok {
+			app.UnauthorizedError(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBody))
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		existing, ok, err := app.models.Idempotency.Begin(r.Context(), userId, key, requestHash, idempotencyKeyTTL)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		if !ok {
+			switch {
+			case existing.RequestHash != requestHash:
+				app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "the Idempotency-Key was already used for a different request")
+			case existing.StatusCode == nil:
+				app.WriteErrorCode(w, r, http.StatusConflict, CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being handled")
+			default:
+				for name, values := range existing.Headers {
+					w.Header()[http.CanonicalHeaderKey(name)] = values
+				}
+
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(*existing.StatusCode)
+				w.Write(existing.Body)
+			}
+
+			return
+		}
+
+		var response bytes.Buffer
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&response)
+
+		// The key must not stay claimed when the handler panics or fails,
+		// or every retry would be told the request is in progress.
+		completed := false
+		ctx := context.WithoutCancel(r.Context())
+
+		defer func() {
+			if completed {
+				return
+			}
+
+			err := app.models.Idempotency.Release(ctx, userId, key)
+
+			if err != nil {
+				app.errorLog.Println(err)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		headers := make(map[string][]string)
+
+		for _, name := range replayedHeaders {
+			if values := ww.Header().Values(name); len(values) > 0 {
+				headers[name] = values
+			}
+		}
+
+		err = app.models.Idempotency.Complete(ctx, userId, key, status, headers, response.Bytes())
+
+		if err != nil {
+			app.errorLog.Println(err)
+			return
+		}
+
+		completed = true
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdempotencyReplaysHeaders(t *testing.T) {
+	app := newTestApp(t)
+	calls := 0
+
+	handler := app.Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/stores/7")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Request-Id", "first")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 7}`))
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/stores", strings.NewReader(`{"name": "Casa"}`))
+		r.Header.Set("Idempotency-Key", "key")
+		r = r.WithContext(context.WithValue(r.Context(), CurrentUserIDKey, "user"))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	send()
+	w := send()
+
+	if calls != 1 {
+		t.Fatalf("the handler ran %d times, want once", calls)
+	}
+
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id": 7}` {
+		t.Errorf("got %d %s, want the first response", w.Code, w.Body)
+	}
+
+	want := map[string]string{
+		"Content-Type":        "application/json",
+		"Location":            "/stores/7",
+		"ETag":                `"v1"`,
+		"Idempotent-Replayed": "true",
+		"X-Request-Id":        "",
+	}
+
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("got %s %q, want %q", name, got, value)
+		}
+	}
+}
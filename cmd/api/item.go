@@ -1,9 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	. "github.com/Piccio-Code/MealStore/internal/data"
-	"github.com/go-playground/validator/v10"
+	"github.com/Piccio-Code/MealStore/internal/filters"
 	"net/http"
 	"strconv"
 )
@@ -26,8 +27,7 @@ func (app *application) listItemsHandler(w http.ResponseWriter, r *http.Request)
 	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -45,15 +45,35 @@ func (app *application) listItemsHandler(w http.ResponseWriter, r *http.Request)
 		onlyWarnings = parsed
 	}
 
-	items, err := app.models.Items.List(r.Context(), storeId, onlyWarnings)
+	capacity, err := filters.NewRange(r.URL.Query(), "capacity")
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	forecastFilters, err := NewForecastFilters(r.URL.Query())
+
+	if err != nil {
+		app.ValidationError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelop{"items": items})
+	pageFilters, err := filters.New(r.URL.Query(), "name", "current_capacity", "created_at", "modified_at", "days_remaining")
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	items, metadata, err := app.models.Items.ListPage(r.Context(), storeId, onlyWarnings, capacity, forecastFilters, pageFilters)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"items": items, "metadata": metadata})
 
 	if err != nil {
 		app.errorLog.Println(err)
@@ -80,18 +100,21 @@ func (app *application) getItemsId(w http.ResponseWriter, r *http.Request) {
 	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
-	storeName := r.URL.Query().Get("store_name")
+	// store_name is the old, misleading name of the parameter.
+	itemName := r.URL.Query().Get("item_name")
+
+	if itemName == "" {
+		itemName = r.URL.Query().Get("store_name")
+	}
 
-	itemId, err := app.models.Items.GetId(r.Context(), storeName, storeId)
+	itemId, err := app.models.Items.GetId(r.Context(), itemName, storeId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -104,6 +127,11 @@ func (app *application) getItemsId(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// itemOption is how an item is shown in pickers: its name and capacity.
+func itemOption(item Item) string {
+	return fmt.Sprintf("%s (%d)", *item.Name, *item.CurrentCapacity)
+}
+
 func (app *application) getItemsOptionsHandler(w http.ResponseWriter, r *http.Request) {
 	storeId, ok := r.Context().Value(StoreIdKey).(int)
 
@@ -122,16 +150,14 @@ func (app *application) getItemsOptionsHandler(w http.ResponseWriter, r *http.Re
 	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	items, err := app.models.Items.List(r.Context(), storeId, false)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -143,7 +169,7 @@ func (app *application) getItemsOptionsHandler(w http.ResponseWriter, r *http.Re
 		name := *item.Name
 		capacity := *item.CurrentCapacity
 
-		options = append(options, OptionStruct{fmt.Sprintf("%s (%d)", name, capacity)})
+		options = append(options, OptionStruct{itemOption(item)})
 		currentCap[name] = capacity
 		versions[name] = *item.Version
 	}
@@ -168,7 +194,7 @@ func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newItem)
 
 	if err != nil {
@@ -192,8 +218,7 @@ func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Reques
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -202,11 +227,12 @@ func (app *application) createItemsHandler(w http.ResponseWriter, r *http.Reques
 	err = app.models.Items.Insert(r.Context(), &newItem)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
+	app.emit(r.Context(), userId, storeId, EventItemCreated, newItem)
+
 	err = app.writeJSON(w, http.StatusCreated, envelop{"new_item": newItem})
 
 	if err != nil {
@@ -229,7 +255,7 @@ func (app *application) createItemsListHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newItemsList)
 
 	if err != nil {
@@ -253,18 +279,21 @@ func (app *application) createItemsListHandler(w http.ResponseWriter, r *http.Re
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	err = app.models.Items.InsertList(r.Context(), newItemsList.Items, storeId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.WriteError(w, r, http.StatusBadRequest, err.Error())
+		app.DBError(w, r, err)
 		return
 	}
+
+	for _, item := range newItemsList.Items {
+		app.emit(r.Context(), userId, storeId, EventItemCreated, item)
+	}
+
 	err = app.writeJSON(w, http.StatusCreated, envelop{"new_items": newItemsList.Items})
 
 	if err != nil {
@@ -299,16 +328,18 @@ func (app *application) getItemsHandler(w http.ResponseWriter, r *http.Request)
 	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	item, err := app.models.Items.Get(r.Context(), itemId, storeId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
+		return
+	}
+
+	if app.notModified(w, r, item.Version) {
 		return
 	}
 
@@ -332,7 +363,7 @@ func (app *application) updateItemsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newItemReq)
 
 	if err != nil {
@@ -357,16 +388,14 @@ func (app *application) updateItemsHandler(w http.ResponseWriter, r *http.Reques
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
 	oldItem, err := app.models.Items.Get(r.Context(), *newItemReq.Id, storeId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -378,6 +407,20 @@ func (app *application) updateItemsHandler(w http.ResponseWriter, r *http.Reques
 		newItemReq.CurrentCapacity = oldItem.CurrentCapacity
 	}
 
+	// If-Match takes the place of the version in the body.
+	if hasIfMatch(r) {
+		if app.preconditionFailed(w, r, oldItem.Version) {
+			return
+		}
+
+		newItemReq.Version = oldItem.Version
+	}
+
+	if newItemReq.Version == nil {
+		app.WriteErrorCode(w, r, http.StatusPreconditionRequired, CodeVersionRequired, "send the version in the body or in an If-Match header")
+		return
+	}
+
 	newItem := Item{
 		Id:              newItemReq.Id,
 		Name:            newItemReq.Name,
@@ -389,11 +432,15 @@ func (app *application) updateItemsHandler(w http.ResponseWriter, r *http.Reques
 	err = app.models.Items.Update(r.Context(), &newItem)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.conditionalError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(newItem.Version))
+
+	app.emit(r.Context(), userId, storeId, EventItemUpdated, newItem)
+	app.checkLowStock(userId, storeId, *newItem.Id)
+
 	err = app.writeJSON(w, http.StatusNoContent, envelop{"updated_item": newItem})
 
 	if err != nil {
@@ -403,10 +450,23 @@ func (app *application) updateItemsHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// itemUpdateReport is the outcome of one entry of a partial list update.
+type itemUpdateReport struct {
+	Index  int            `json:"index"`
+	Id     int            `json:"id"`
+	Status string         `json:"status"`
+	Item   *Item          `json:"item,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+}
+
+type updateItemsListRequest struct {
+	UpdateItemList []*UpdateItem `json:"items,omitempty" validate:"required,min=1,dive,required"`
+}
+
+// updateItemsListHandler applies the whole list or nothing. With
+// ?mode=partial it applies every entry it can and reports each one.
 func (app *application) updateItemsListHandler(w http.ResponseWriter, r *http.Request) {
-	var newItemsReq struct {
-		UpdateItemList []*UpdateItem `json:"items,omitempty"`
-	}
+	var newItemsReq updateItemsListRequest
 
 	err := app.readeJSON(r, &newItemsReq)
 
@@ -416,6 +476,32 @@ func (app *application) updateItemsListHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	v := newValidator()
+	err = v.Struct(newItemsReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	partial := false
+
+	switch r.URL.Query().Get("mode") {
+	case "", "atomic":
+	case "partial":
+		partial = true
+	default:
+		app.WriteError(w, r, http.StatusBadRequest, "mode must be atomic or partial")
+		return
+	}
+
+	for _, newItemReq := range newItemsReq.UpdateItemList {
+		if newItemReq.Version == nil {
+			app.WriteErrorCode(w, r, http.StatusPreconditionRequired, CodeVersionRequired, "every item needs its version")
+			return
+		}
+	}
+
 	storeId, ok := r.Context().Value(StoreIdKey).(int)
 
 	if !ok {
@@ -432,51 +518,211 @@ func (app *application) updateItemsListHandler(w http.ResponseWriter, r *http.Re
 
 	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
 
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	results, err := app.models.Items.UpdateList(r.Context(), storeId, newItemsReq.UpdateItemList, partial)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	newItems := make([]Item, 0, len(results))
+	reports := make([]itemUpdateReport, 0, len(results))
+	var itemIds []int
+
+	for i, result := range results {
+		report := itemUpdateReport{Index: i, Id: *newItemsReq.UpdateItemList[i].Id, Status: "updated"}
+
+		if result.Err != nil {
+			_, body := app.dbErrorResponse(result.Err)
+			report.Status = "failed"
+			report.Error = &body
+			reports = append(reports, report)
+			continue
+		}
+
+		report.Item = &results[i].Item
+		reports = append(reports, report)
+		newItems = append(newItems, result.Item)
+		itemIds = append(itemIds, *result.Item.Id)
+	}
+
+	for _, item := range newItems {
+		app.emit(r.Context(), userId, storeId, EventItemUpdated, item)
+	}
+
+	app.checkLowStock(userId, storeId, itemIds...)
+
+	if partial {
+		err = app.writeJSON(w, http.StatusOK, envelop{
+			"results": reports,
+			"updated": len(newItems),
+			"failed":  len(reports) - len(newItems),
+		})
+	} else {
+		err = app.writeJSON(w, http.StatusOK, envelop{"updated_items": newItems})
+	}
+
 	if err != nil {
 		app.errorLog.Println(err)
 		app.BadRequestError(w, r)
 		return
 	}
+}
 
-	var newItems []Item
+func (app *application) deleteItemsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
 
-	for _, newItemReq := range newItemsReq.UpdateItemList {
-		oldItem, err := app.models.Items.Get(r.Context(), *newItemReq.Id, storeId)
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	itemId, ok := r.Context().Value(ItemIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	var version *string
+
+	if hasIfMatch(r) {
+		item, err := app.models.Items.Get(r.Context(), itemId, storeId)
 
 		if err != nil {
-			app.errorLog.Println(err)
-			app.BadRequestError(w, r)
+			app.DBError(w, r, err)
 			return
 		}
 
-		if newItemReq.Name == nil {
-			newItemReq.Name = oldItem.Name
+		if app.preconditionFailed(w, r, item.Version) {
+			return
 		}
 
-		if newItemReq.CurrentCapacity == nil {
-			newItemReq.CurrentCapacity = oldItem.CurrentCapacity
-		}
+		version = item.Version
+	}
 
-		newItem := Item{
-			Id:              newItemReq.Id,
-			Name:            newItemReq.Name,
-			StoreId:         storeId,
-			CurrentCapacity: newItemReq.CurrentCapacity,
-			Version:         newItemReq.Version,
-		}
+	err = app.models.Items.Delete(r.Context(), itemId, storeId, version)
+
+	if err != nil {
+		app.conditionalError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_item_id": itemId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+// mergeItemsHandler folds the source item of the body into the item of the
+// path and returns what is left.
+func (app *application) mergeItemsHandler(w http.ResponseWriter, r *http.Request) {
+	var mergeReq MergeItems
+
+	err := app.readeJSON(r, &mergeReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(mergeReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	itemId, ok := r.Context().Value(ItemIdKey).(int)
 
-		err = app.models.Items.Update(r.Context(), &newItem)
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	if *mergeReq.SourceId == itemId {
+		app.WriteError(w, r, http.StatusBadRequest, "an item can't be merged into itself")
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	// If-Match takes the place of the target version in the body.
+	if hasIfMatch(r) {
+		target, err := app.models.Items.Get(r.Context(), itemId, storeId)
 
 		if err != nil {
-			app.errorLog.Println(err)
-			app.BadRequestError(w, r)
+			app.DBError(w, r, err)
 			return
 		}
 
-		newItems = append(newItems, newItem)
+		if app.preconditionFailed(w, r, target.Version) {
+			return
+		}
+
+		mergeReq.Version = target.Version
+	}
+
+	if mergeReq.Version == nil {
+		app.WriteErrorCode(w, r, http.StatusPreconditionRequired, CodeVersionRequired, "send the version in the body or in an If-Match header")
+		return
+	}
+
+	item, err := app.models.Items.Merge(r.Context(), storeId, itemId, *mergeReq.Version, *mergeReq.SourceId, *mergeReq.SourceVersion)
+
+	if err != nil {
+		app.conditionalError(w, r, err)
+		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelop{"updated_items": newItems})
+	w.Header().Set("ETag", etag(item.Version))
+
+	app.emit(r.Context(), userId, storeId, EventItemUpdated, item)
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"merged_item": item, "deleted_item_id": *mergeReq.SourceId})
 
 	if err != nil {
 		app.errorLog.Println(err)
@@ -485,7 +731,27 @@ func (app *application) updateItemsListHandler(w http.ResponseWriter, r *http.Re
 	}
 }
 
-func (app *application) deleteItemsHandler(w http.ResponseWriter, r *http.Request) {
+// transferItemsHandler moves a quantity, or the whole item, to another of
+// the user's stores.
+func (app *application) transferItemsHandler(w http.ResponseWriter, r *http.Request) {
+	var transferReq TransferItem
+
+	err := app.readeJSON(r, &transferReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(transferReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
 	storeId, ok := r.Context().Value(StoreIdKey).(int)
 
 	if !ok {
@@ -507,23 +773,204 @@ func (app *application) deleteItemsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+	if *transferReq.TargetStoreId == storeId {
+		app.WriteError(w, r, http.StatusBadRequest, "the item is already in that store")
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), *transferReq.TargetStoreId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	// If-Match takes the place of the version in the body.
+	if hasIfMatch(r) {
+		item, err := app.models.Items.Get(r.Context(), itemId, storeId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		if app.preconditionFailed(w, r, item.Version) {
+			return
+		}
+
+		transferReq.Version = item.Version
+	}
+
+	if transferReq.Version == nil {
+		app.WriteErrorCode(w, r, http.StatusPreconditionRequired, CodeVersionRequired, "send the version in the body or in an If-Match header")
+		return
+	}
+
+	result, err := app.models.Items.Transfer(r.Context(), storeId, itemId, *transferReq.Version, transferReq)
+
+	if errors.Is(err, ErrNotEnoughStock) {
+		app.WriteErrorCode(w, r, http.StatusConflict, CodeNotEnoughStock, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.conditionalError(w, r, err)
+		return
+	}
+
+	if result.Source != nil {
+		app.emit(r.Context(), userId, storeId, EventItemUpdated, result.Source)
+		app.checkLowStock(userId, storeId, *result.Source.Id)
+	}
+
+	if result.Created {
+		app.emit(r.Context(), userId, result.Target.StoreId, EventItemCreated, result.Target)
+	} else {
+		app.emit(r.Context(), userId, result.Target.StoreId, EventItemUpdated, result.Target)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"transfer": result})
 
 	if err != nil {
 		app.errorLog.Println(err)
 		app.BadRequestError(w, r)
 		return
 	}
+}
+
+// useFirst picks up to limit of the stale items, idle for at least
+// minIdleDays, as the "use these first" list.
+func useFirst(items []StaleItem, limit int, minIdleDays float64) []OptionStruct {
+	options := make([]OptionStruct, 0)
+
+	for _, item := range items {
+		if len(options) == limit {
+			break
+		}
+
+		if item.IdleDays < minIdleDays {
+			continue
+		}
+
+		options = append(options, OptionStruct{fmt.Sprintf("%s (%d, %d days)", *item.Name, *item.CurrentCapacity, int(item.IdleDays))})
+	}
+
+	return options
+}
+
+func (app *application) listStaleItemsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	limit := 5
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+
+		if err != nil || parsed < 1 {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		limit = parsed
+	}
+
+	minIdleDays := 7.0
+
+	if val := r.URL.Query().Get("min_idle_days"); val != "" {
+		parsed, err := strconv.ParseFloat(val, 64)
+
+		if err != nil || parsed < 0 {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		minIdleDays = parsed
+	}
+
+	items, err := app.models.Items.Stale(r.Context(), storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
 
-	err = app.models.Items.Delete(r.Context(), itemId, storeId)
+	err = app.writeJSON(w, http.StatusOK, envelop{"stale_items": items, "use_first": useFirst(items, limit, minIdleDays)})
 
 	if err != nil {
 		app.errorLog.Println(err)
 		app.BadRequestError(w, r)
 		return
 	}
+}
 
-	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_item_id": itemId})
+func (app *application) forecastItemsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	filters, err := NewForecastFilters(r.URL.Query())
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	forecasts, err := app.models.EatenItems.Forecast(r.Context(), storeId, filters)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	SortByDaysRemaining(forecasts)
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"forecast": forecasts})
 
 	if err != nil {
 		app.errorLog.Println(err)
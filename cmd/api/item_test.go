@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateItemsListValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{"rename only", `{"items": [{"id": 1, "name": "Latte", "version": "v"}]}`, true},
+		{"capacity only", `{"items": [{"id": 1, "current_capacity": 3, "version": "v"}]}`, true},
+		{"name and capacity", `{"items": [{"id": 1, "name": "Latte", "current_capacity": 3, "version": "v"}]}`, true},
+		{"capacity below one", `{"items": [{"id": 1, "current_capacity": 0, "version": "v"}]}`, false},
+		{"missing id", `{"items": [{"name": "Latte", "version": "v"}]}`, false},
+		{"null entry", `{"items": [null]}`, false},
+		{"empty list", `{"items": []}`, false},
+	}
+
+	v := newValidator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req updateItemsListRequest
+
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			err := v.Struct(req)
+
+			if tt.valid && err != nil {
+				t.Errorf("got %v, want no error", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Error("got no error, want a validation error")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"github.com/Piccio-Code/MealStore/internal/notify"
+	"strings"
+	"time"
+)
+
+const jobRunsRetention = 30 * 24 * time.Hour
+
+// The daily digest lists, per store, the items idle for at least a week.
+const (
+	digestItemsPerStore = 5
+	digestMinIdleDays   = 7.0
+)
+
+func (app *application) registerJobs() error {
+	err := app.scheduler.Register("low-stock-scan", "0 * * * *", time.Minute, app.lowStockScanJob)
+
+	if err != nil {
+		return err
+	}
+
+	err = app.scheduler.Register("daily-digest", "0 9 * * *", 5*time.Minute, app.dailyDigestJob)
+
+	if err != nil {
+		return err
+	}
+
+	err = app.scheduler.Register("job-runs-cleanup", "30 3 * * *", time.Minute, app.jobRunsCleanupJob)
+
+	if err != nil {
+		return err
+	}
+
+	err = app.scheduler.Register("idempotency-keys-cleanup", "45 * * * *", time.Minute, app.idempotencyKeysCleanupJob)
+
+	if err != nil {
+		return err
+	}
+
+	return app.scheduler.Register("trash-purge", "15 4 * * *", 5*time.Minute, app.trashPurgeJob)
+}
+
+func (app *application) lowStockScanJob(ctx context.Context) error {
+	items, err := app.models.Items.ListLow(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	perStore := make(map[string]int)
+
+	for _, item := range items {
+		perStore[item.StoreName]++
+
+		app.alertLowStock(ctx, item.UserId, item.StoreName, item.Item)
+	}
+
+	for store, count := range perStore {
+		app.infoLog.Printf("low-stock-scan: %d items running low in %s", count, store)
+	}
+
+	return nil
+}
+
+// dailyDigestJob sends every user who wants it the "use these first" list
+// of each of their stores. Users with nothing stale get no message.
+func (app *application) dailyDigestJob(ctx context.Context) error {
+	stores, err := app.models.Stores.ListAll(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	digests := make(map[string][]string)
+	var users []string
+
+	for _, store := range stores {
+		items, err := app.models.Items.Stale(ctx, *store.ID)
+
+		if err != nil {
+			return err
+		}
+
+		options := useFirst(items, digestItemsPerStore, digestMinIdleDays)
+
+		if len(options) == 0 {
+			continue
+		}
+
+		if _, ok := digests[*store.UserID]; !ok {
+			users = append(users, *store.UserID)
+		}
+
+		lines := []string{"\n" + *store.Name + ":"}
+
+		for _, option := range options {
+			lines = append(lines, "- "+option.Option)
+		}
+
+		digests[*store.UserID] = append(digests[*store.UserID], lines...)
+	}
+
+	sent := 0
+
+	for _, userId := range users {
+		prefs, err := app.models.Notifications.GetPreferences(ctx, userId)
+
+		if err != nil {
+			return err
+		}
+
+		if !*prefs.Digest {
+			continue
+		}
+
+		msg := notify.Message{
+			ChatId: *prefs.ChatId,
+			Text:   "Use these first:\n" + strings.Join(digests[userId], "\n"),
+		}
+
+		// One undeliverable chat must not keep the others from their digest.
+		if err := app.notifier.Send(ctx, msg); err != nil {
+			app.errorLog.Println(err)
+			continue
+		}
+
+		sent++
+	}
+
+	app.infoLog.Printf("daily-digest: sent %d of %d digests", sent, len(users))
+
+	return nil
+}
+
+func (app *application) jobRunsCleanupJob(ctx context.Context) error {
+	deleted, err := app.models.JobRuns.Cleanup(ctx, time.Now().Add(-jobRunsRetention))
+
+	if err != nil {
+		return err
+	}
+
+	app.infoLog.Printf("job-runs-cleanup: deleted %d runs", deleted)
+
+	return nil
+}
+
+func (app *application) idempotencyKeysCleanupJob(ctx context.Context) error {
+	deleted, err := app.models.Idempotency.Cleanup(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	app.infoLog.Printf("idempotency-keys-cleanup: deleted %d keys", deleted)
+
+	return nil
+}
+
+func (app *application) trashPurgeJob(ctx context.Context) error {
+	before := time.Now().Add(-app.config.trashRetention)
+
+	items, err := app.models.Items.Purge(ctx, before)
+
+	if err != nil {
+		return err
+	}
+
+	stores, err := app.models.Stores.Purge(ctx, before)
+
+	if err != nil {
+		return err
+	}
+
+	app.infoLog.Printf("trash-purge: deleted %d items and %d stores", items, stores)
+
+	return nil
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/notify"
+	"github.com/Piccio-Code/MealStore/internal/scheduler"
+	"github.com/Piccio-Code/MealStore/internal/webhook"
 	"log"
 	"net/http"
 	"os"
@@ -17,16 +20,25 @@ import (
 const version = "1.0.0"
 
 type config struct {
-	port int
-	env  string
+	port      int
+	env       string
+	adminId   string
+	scheduler bool
+
+	// trashRetention is how long deleted stores and items can be restored.
+	trashRetention time.Duration
+
+	telegramSecret string
 }
 
 type application struct {
-	infoLog  *log.Logger
-	errorLog *log.Logger
-	pool     *pgxpool.Pool
-	models   data.Models
-	config   config
+	infoLog   *log.Logger
+	errorLog  *log.Logger
+	pool      *pgxpool.Pool
+	models    data.Models
+	scheduler *scheduler.Scheduler
+	notifier  notify.Notifier
+	config    config
 }
 
 func main() {
@@ -39,8 +51,13 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 8080, "The port of the backend.")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.BoolVar(&cfg.scheduler, "scheduler", true, "Run the background jobs and webhook deliveries in this instance.")
+	flag.DurationVar(&cfg.trashRetention, "trash-retention", 30*24*time.Hour, "How long deleted stores and items stay in the trash.")
 	flag.Parse()
 
+	cfg.adminId = os.Getenv("ADMIN_CHAT_ID")
+	cfg.telegramSecret = os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+
 	infoLog := log.New(os.Stdout, "INFO:\t", log.LstdFlags)
 	errorLog := log.New(os.Stderr, "ERROR:\t", log.LstdFlags|log.Lshortfile)
 
@@ -52,12 +69,35 @@ func main() {
 
 	defer pool.Close()
 
+	models := data.NewModels(pool)
+
+	var notifier notify.Notifier = notify.Log{Logger: infoLog}
+
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		notifier = notify.NewTelegram(os.Getenv("TELEGRAM_API_URL"), token)
+	}
+
 	app := &application{
-		infoLog:  infoLog,
-		errorLog: errorLog,
-		pool:     pool,
-		models:   data.NewModels(pool),
-		config:   cfg,
+		infoLog:   infoLog,
+		errorLog:  errorLog,
+		pool:      pool,
+		models:    models,
+		scheduler: scheduler.New(models.JobRuns, infoLog, errorLog),
+		notifier:  notifier,
+		config:    cfg,
+	}
+
+	err = app.registerJobs()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cfg.scheduler {
+		go app.scheduler.Start(ctx)
+		go webhook.NewDispatcher(models.Webhooks, infoLog, errorLog).Start(ctx, 5*time.Second)
 	}
 
 	srv := &http.Server{
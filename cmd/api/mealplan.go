@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"net/http"
+	"strconv"
+)
+
+func (app *application) createMealPlanSlotHandler(w http.ResponseWriter, r *http.Request) {
+	var newSlot MealPlanSlot
+
+	err := app.readeJSON(r, &newSlot)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newSlot)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	newSlot.UserId = userId
+
+	err = app.models.MealPlans.Insert(r.Context(), &newSlot)
+
+	if errors.Is(err, ErrUnknownItem) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownItem, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelop{"new_slot": newSlot})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listMealPlanHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	filters, err := NewMealPlanFilters(r.URL.Query())
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	slots, err := app.models.MealPlans.List(r.Context(), userId, filters)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"meal_plan": slots})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) getMealPlanSlotHandler(w http.ResponseWriter, r *http.Request) {
+	slotId, ok := r.Context().Value(SlotIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	slot, err := app.models.MealPlans.Get(r.Context(), slotId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"slot": slot})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) updateMealPlanSlotHandler(w http.ResponseWriter, r *http.Request) {
+	var newSlotReq UpdateMealPlanSlot
+
+	err := app.readeJSON(r, &newSlotReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newSlotReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	oldSlot, err := app.models.MealPlans.Get(r.Context(), *newSlotReq.Id, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if newSlotReq.PlanDate == nil {
+		newSlotReq.PlanDate = oldSlot.PlanDate
+	}
+
+	if newSlotReq.MealType == nil {
+		newSlotReq.MealType = oldSlot.MealType
+	}
+
+	if newSlotReq.DishName == nil {
+		newSlotReq.DishName = oldSlot.DishName
+	}
+
+	newSlot := MealPlanSlot{
+		Id:       newSlotReq.Id,
+		PlanDate: newSlotReq.PlanDate,
+		MealType: newSlotReq.MealType,
+		DishName: newSlotReq.DishName,
+		UserId:   userId,
+		Items:    newSlotReq.Items,
+		Version:  newSlotReq.Version,
+	}
+
+	err = app.models.MealPlans.Update(r.Context(), &newSlot)
+
+	if errors.Is(err, ErrUnknownItem) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownItem, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"updated_slot": newSlot})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) deleteMealPlanSlotHandler(w http.ResponseWriter, r *http.Request) {
+	slotId, ok := r.Context().Value(SlotIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	err := app.models.MealPlans.Delete(r.Context(), slotId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_slot_id": slotId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) mealPlanProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	days := 7
+
+	if val := r.URL.Query().Get("days"); val != "" {
+		parsed, err := strconv.Atoi(val)
+
+		if err != nil || parsed < 1 || parsed > 31 {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		days = parsed
+	}
+
+	projections, err := app.models.MealPlans.Projection(r.Context(), storeId, userId, days)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"projection": projections})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
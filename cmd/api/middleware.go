@@ -8,17 +8,35 @@ import (
 type CurrentUserID string
 type StoreId string
 type ItemId string
+type RecipeId string
+type PresetId string
+type SlotId string
+type ListId string
+type EntryId string
+type WebhookId string
+type DeliveryId string
 
 const CurrentUserIDKey = CurrentUserID("CurrentUserIDKey")
 const StoreIdKey = StoreId("StoreIdKey")
 const ItemIdKey = ItemId("ItemIdKey")
+const RecipeIdKey = RecipeId("RecipeIdKey")
+const PresetIdKey = PresetId("PresetIdKey")
+const SlotIdKey = SlotId("SlotIdKey")
+const ListIdKey = ListId("ListIdKey")
+const EntryIdKey = EntryId("EntryIdKey")
+const WebhookIdKey = WebhookId("WebhookIdKey")
+const DeliveryIdKey = DeliveryId("DeliveryIdKey")
+
+// allowedChatId is the only Telegram chat let through, both on the API and
+// by the bot.
+const allowedChatId = "7202833466"
 
 func (app *application) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			id := r.Header.Get("X-Telegram-Chat-ID")
 
-			if id != "7202833466" {
+			if id != allowedChatId {
 				app.errorLog.Println("error HERE: ", id)
 				app.UnauthorizedError(w, r)
 				return
@@ -32,6 +50,19 @@ func (app *application) AuthMiddleware(next http.Handler) http.Handler {
 	)
 }
 
+func (app *application) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+		if !ok || app.config.adminId == "" || userId != app.config.adminId {
+			app.UnauthorizedError(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) RequireStoreId(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -67,3 +98,204 @@ func (app *application) RequireItemId(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) RequireRecipeId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		recipeId, err := app.getIdParam(r, "recipe_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), RecipeIdKey, recipeId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequirePresetId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		presetId, err := app.getIdParam(r, "preset_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), PresetIdKey, presetId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireSlotId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		slotId, err := app.getIdParam(r, "slot_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), SlotIdKey, slotId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireListId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		listId, err := app.getIdParam(r, "list_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ListIdKey, listId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireEntryId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		entryId, err := app.getIdParam(r, "entry_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), EntryIdKey, entryId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireWebhookId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		webhookId, err := app.getIdParam(r, "webhook_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), WebhookIdKey, webhookId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) RequireDeliveryId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		deliveryId, err := app.getIdParam(r, "delivery_id")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), DeliveryIdKey, deliveryId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireStoreName resolves {store_name} to the store id, so the handlers
+// of /v1/store/{store_id} also serve /v1/stores/by-name/{store_name}.
+func (app *application) RequireStoreName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+		if !ok {
+			app.UnauthorizedError(w, r)
+			return
+		}
+
+		storeName, err := app.getNameParam(r, "store_name")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		storeId, err := app.models.Stores.GetID(r.Context(), storeName, userId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), StoreIdKey, storeId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireItemName resolves {store_name} and {item_name} together in a
+// single query.
+func (app *application) RequireItemName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+		if !ok {
+			app.UnauthorizedError(w, r)
+			return
+		}
+
+		storeName, err := app.getNameParam(r, "store_name")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		itemName, err := app.getNameParam(r, "item_name")
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		storeId, itemId, err := app.models.Items.GetIdByNames(r.Context(), storeName, itemName, userId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), StoreIdKey, storeId)
+		ctx = context.WithValue(ctx, ItemIdKey, itemId)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
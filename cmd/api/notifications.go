@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/notify"
+	"net/http"
+	"time"
+)
+
+// checkLowStock sends a low-stock alert for every given item that is at or
+// below the warning threshold and re-arms the alert of those that are not.
+// It runs in the background so that a slow sender never delays a response.
+func (app *application) checkLowStock(userId string, storeId int, itemIds ...int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		store, err := app.models.Stores.Get(ctx, storeId, userId)
+
+		if err != nil {
+			app.errorLog.Println(err)
+			return
+		}
+
+		for _, itemId := range itemIds {
+			item, err := app.models.Items.Get(ctx, itemId, storeId)
+
+			if err != nil {
+				app.errorLog.Println(err)
+				continue
+			}
+
+			app.alertLowStock(ctx, userId, *store.Name, item)
+		}
+	}()
+}
+
+func (app *application) alertLowStock(ctx context.Context, userId, storeName string, item Item) {
+	if *item.CurrentCapacity > WarningThreshold {
+		err := app.models.Notifications.ClearAlert(ctx, *item.Id)
+
+		if err != nil {
+			app.errorLog.Println(err)
+		}
+
+		return
+	}
+
+	prefs, err := app.models.Notifications.GetPreferences(ctx, userId)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		return
+	}
+
+	claimed, err := app.models.Notifications.ClaimAlert(ctx, *item.Id, *prefs.RepeatAfterHours)
+
+	if err != nil || !claimed {
+		if err != nil {
+			app.errorLog.Println(err)
+		}
+
+		return
+	}
+
+	app.emit(ctx, userId, item.StoreId, EventItemLowStock, item)
+
+	// Webhooks fire regardless, the preference only covers Telegram.
+	if !*prefs.LowStock {
+		return
+	}
+
+	msg := notify.Message{
+		ChatId: *prefs.ChatId,
+		Text:   fmt.Sprintf("Running low: %s in %s (%d left)", *item.Name, storeName, *item.CurrentCapacity),
+	}
+
+	err = app.notifier.Send(ctx, msg)
+
+	if err != nil {
+		app.errorLog.Println(err)
+
+		// Let the next check try again.
+		err = app.models.Notifications.ClearAlert(ctx, *item.Id)
+
+		if err != nil {
+			app.errorLog.Println(err)
+		}
+	}
+}
+
+func (app *application) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	prefs, err := app.models.Notifications.GetPreferences(r.Context(), userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"preferences": prefs})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	var newPrefs NotificationPreferences
+
+	err := app.readeJSON(r, &newPrefs)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newPrefs)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	oldPrefs, err := app.models.Notifications.GetPreferences(r.Context(), userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if newPrefs.ChatId == nil {
+		newPrefs.ChatId = oldPrefs.ChatId
+	}
+
+	if newPrefs.LowStock == nil {
+		newPrefs.LowStock = oldPrefs.LowStock
+	}
+
+	if newPrefs.Digest == nil {
+		newPrefs.Digest = oldPrefs.Digest
+	}
+
+	if newPrefs.RepeatAfterHours == nil {
+		newPrefs.RepeatAfterHours = oldPrefs.RepeatAfterHours
+	}
+
+	newPrefs.UserId = userId
+
+	err = app.models.Notifications.UpsertPreferences(r.Context(), &newPrefs)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"preferences": newPrefs})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/fuzzy"
+	"github.com/Piccio-Code/MealStore/internal/parse"
+	"math"
+	"net/http"
+)
+
+type textInterpretation struct {
+	parse.Entry
+	Item            *Item   `json:"item,omitempty"`
+	MatchConfidence float64 `json:"match_confidence"`
+	Status          string  `json:"status"`
+}
+
+// parseTextHandler shows how a chat message such as "ate 2 eggs and half a
+// pizza" would be read against the store. Nothing is written.
+func (app *application) parseTextHandler(w http.ResponseWriter, r *http.Request) {
+	var parseReq struct {
+		Text string `json:"text" validate:"required,max=500"`
+	}
+
+	err := app.readeJSON(r, &parseReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(parseReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	items, err := app.models.Items.List(r.Context(), storeId, false)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	names := make([]string, len(items))
+
+	for i, item := range items {
+		names[i] = *item.Name
+	}
+
+	entries := parse.Parse(parseReq.Text)
+	interpretation := make([]textInterpretation, 0, len(entries))
+	confidence := 0.0
+
+	for i, entry := range entries {
+		t := textInterpretation{Entry: entry, Status: "unmatched"}
+
+		index, score := fuzzy.Best(entry.Name, names, 0.6)
+		t.MatchConfidence = math.Round(score*100) / 100
+
+		if index >= 0 {
+			t.Item = &items[index]
+			t.Status = "matched"
+		}
+
+		// The whole message is as uncertain as its weakest entry.
+		if overall := entry.Confidence * t.MatchConfidence; i == 0 || overall < confidence {
+			confidence = overall
+		}
+
+		interpretation = append(interpretation, t)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{
+		"text":       parseReq.Text,
+		"entries":    interpretation,
+		"confidence": math.Round(confidence*100) / 100,
+	})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
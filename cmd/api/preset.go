@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"io"
+	"net/http"
+)
+
+func (app *application) createPresetHandler(w http.ResponseWriter, r *http.Request) {
+	var newPreset Preset
+
+	err := app.readeJSON(r, &newPreset)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newPreset)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	newPreset.StoreId = storeId
+
+	err = app.models.Presets.Insert(r.Context(), &newPreset)
+
+	if errors.Is(err, ErrUnknownItem) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownItem, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelop{"new_preset": newPreset})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	presets, err := app.models.Presets.List(r.Context(), storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"presets": presets})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) getPresetHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	presetId, ok := r.Context().Value(PresetIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	preset, err := app.models.Presets.Get(r.Context(), presetId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"preset": preset})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) updatePresetHandler(w http.ResponseWriter, r *http.Request) {
+	var newPresetReq UpdatePreset
+
+	err := app.readeJSON(r, &newPresetReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newPresetReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	oldPreset, err := app.models.Presets.Get(r.Context(), *newPresetReq.Id, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if newPresetReq.Name == nil {
+		newPresetReq.Name = oldPreset.Name
+	}
+
+	newPreset := Preset{
+		Id:      newPresetReq.Id,
+		Name:    newPresetReq.Name,
+		StoreId: storeId,
+		Entries: newPresetReq.Entries,
+		Version: newPresetReq.Version,
+	}
+
+	err = app.models.Presets.Update(r.Context(), &newPreset)
+
+	if errors.Is(err, ErrUnknownItem) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownItem, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"updated_preset": newPreset})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) deletePresetHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	presetId, ok := r.Context().Value(PresetIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.models.Presets.Delete(r.Context(), presetId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_preset_id": presetId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) logPresetHandler(w http.ResponseWriter, r *http.Request) {
+	var logReq struct {
+		Multiplier int `json:"multiplier" validate:"omitempty,gte=1"`
+	}
+
+	err := app.readeJSON(r, &logReq)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(logReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	if logReq.Multiplier == 0 {
+		logReq.Multiplier = 1
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	presetId, ok := r.Context().Value(PresetIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	preset, err := app.models.Presets.Get(r.Context(), presetId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	eatenItems, missing := preset.EatenItems(logReq.Multiplier)
+
+	if len(eatenItems) == 0 {
+		app.WriteErrorCode(w, r, http.StatusBadRequest, CodePresetItemsMissing, "none of the preset items exist anymore")
+		return
+	}
+
+	err = app.models.EatenItems.CreateList(r.Context(), eatenItems)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	for _, item := range eatenItems {
+		app.emit(r.Context(), userId, storeId, EventEatenCreated, item)
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelop{"eaten_items": eatenItems, "missing_item_ids": missing})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/fuzzy"
+	"github.com/Piccio-Code/MealStore/internal/jsonld"
+	"math"
+	"net/http"
+)
+
+type ingredientCoverage struct {
+	jsonld.Ingredient
+	Needed     *float64 `json:"needed"`
+	Item       *Item    `json:"item,omitempty"`
+	Confidence float64  `json:"confidence"`
+	Status     string   `json:"status"`
+}
+
+func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	var newRecipe Recipe
+
+	err := app.readeJSON(r, &newRecipe)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newRecipe)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	newRecipe.StoreId = storeId
+
+	err = app.models.Recipes.Insert(r.Context(), &newRecipe)
+
+	if errors.Is(err, ErrUnknownIngredient) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownIngredient, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelop{"new_recipe": newRecipe})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listRecipesHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	recipes, err := app.models.Recipes.List(r.Context(), storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"recipes": recipes})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) getRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	recipeId, ok := r.Context().Value(RecipeIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	recipe, err := app.models.Recipes.Get(r.Context(), recipeId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"recipe": recipe})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	var newRecipeReq UpdateRecipe
+
+	err := app.readeJSON(r, &newRecipeReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newRecipeReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	oldRecipe, err := app.models.Recipes.Get(r.Context(), *newRecipeReq.Id, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if newRecipeReq.Name == nil {
+		newRecipeReq.Name = oldRecipe.Name
+	}
+
+	if newRecipeReq.Servings == nil {
+		newRecipeReq.Servings = oldRecipe.Servings
+	}
+
+	if newRecipeReq.Instructions == nil {
+		newRecipeReq.Instructions = oldRecipe.Instructions
+	}
+
+	newRecipe := Recipe{
+		Id:           newRecipeReq.Id,
+		Name:         newRecipeReq.Name,
+		Servings:     newRecipeReq.Servings,
+		Instructions: newRecipeReq.Instructions,
+		StoreId:      storeId,
+		Ingredients:  newRecipeReq.Ingredients,
+		Version:      newRecipeReq.Version,
+	}
+
+	err = app.models.Recipes.Update(r.Context(), &newRecipe)
+
+	if errors.Is(err, ErrUnknownIngredient) {
+		app.WriteErrorCode(w, r, http.StatusUnprocessableEntity, CodeUnknownIngredient, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"updated_recipe": newRecipe})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	recipeId, ok := r.Context().Value(RecipeIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.models.Recipes.Delete(r.Context(), recipeId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_recipe_id": recipeId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) checkRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	var checkReq struct {
+		Recipe   json.RawMessage `json:"recipe" validate:"required"`
+		Servings float64         `json:"servings" validate:"omitempty,gt=0"`
+	}
+
+	err := app.readeJSON(r, &checkReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(checkReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	recipe, err := jsonld.ParseRecipe(checkReq.Recipe)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.WriteErrorCode(w, r, http.StatusBadRequest, CodeUnsupportedDocument, err.Error())
+		return
+	}
+
+	items, err := app.models.Items.List(r.Context(), storeId, false)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	names := make([]string, len(items))
+
+	for i, item := range items {
+		names[i] = *item.Name
+	}
+
+	scale := 1.0
+
+	if checkReq.Servings > 0 && recipe.Yield > 0 {
+		scale = checkReq.Servings / recipe.Yield
+	}
+
+	coverage := make([]ingredientCoverage, 0, len(recipe.Ingredients))
+	covered := true
+	unmatched := 0
+
+	for _, ingredient := range recipe.Ingredients {
+		c := ingredientCoverage{Ingredient: ingredient, Status: "unmatched"}
+
+		if ingredient.Quantity != nil {
+			needed := *ingredient.Quantity * scale
+			c.Needed = &needed
+		}
+
+		index, score := fuzzy.Best(ingredient.Name, names, 0.6)
+		c.Confidence = math.Round(score*100) / 100
+
+		if index >= 0 {
+			c.Item = &items[index]
+			capacity := float64(*c.Item.CurrentCapacity)
+
+			switch {
+			case capacity <= 0:
+				c.Status = "short"
+			case c.Needed != nil && ingredient.Countable() && capacity < math.Ceil(*c.Needed):
+				c.Status = "short"
+			case c.Needed != nil && ingredient.Countable():
+				c.Status = "covered"
+			default:
+				// The amount is in grams, cups... which cannot be compared
+				// with a piece count, so only presence is known.
+				c.Status = "in_stock"
+			}
+		} else {
+			unmatched++
+		}
+
+		if c.Status == "short" || c.Status == "unmatched" {
+			covered = false
+		}
+
+		coverage = append(coverage, c)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{
+		"recipe_name": recipe.Name,
+		"yield":       recipe.Yield,
+		"scale":       scale,
+		"covered":     covered,
+		"unmatched":   unmatched,
+		"coverage":    coverage,
+	})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
@@ -13,10 +13,49 @@ func (app *application) routes() *chi.Mux {
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 
+	router.NotFound(app.NotFoundError)
+	router.MethodNotAllowed(app.MethodNotAllowedError)
+
 	router.Get("/v1/healthcheck", app.healthcheckHandler)
 
+	// Telegram authenticates with its secret token header instead.
+	router.Post("/telegram/webhook", app.telegramWebhookHandler)
+
 	router.Group(func(r chi.Router) {
 		r.Use(app.AuthMiddleware)
+		r.Use(app.Idempotency)
+
+		// Admin
+		r.Group(func(r chi.Router) {
+			r.Use(app.RequireAdmin)
+
+			r.Get("/v1/admin/jobs", app.listJobsHandler)
+			r.Get("/v1/admin/jobs/{job_name}/runs", app.listJobRunsHandler)
+			r.Post("/v1/admin/jobs/{job_name}/run", app.triggerJobHandler)
+		})
+
+		// Notifications
+		r.Get("/v1/notifications/preferences", app.getNotificationPreferencesHandler)
+		r.Put("/v1/notifications/preferences", app.updateNotificationPreferencesHandler)
+
+		// Webhooks
+		r.Post("/v1/webhooks", app.createWebhookHandler)
+		r.Get("/v1/webhooks", app.listWebhooksHandler)
+		r.Put("/v1/webhooks", app.updateWebhookHandler)
+
+		// Webhook by ID
+		r.Group(func(r chi.Router) {
+			r.Use(app.RequireWebhookId)
+
+			r.Get("/v1/webhooks/{webhook_id}", app.getWebhookHandler)
+			r.Delete("/v1/webhooks/{webhook_id}", app.deleteWebhookHandler)
+			r.Get("/v1/webhooks/{webhook_id}/deliveries", app.listWebhookDeliveriesHandler)
+
+			r.With(app.RequireDeliveryId).Post("/v1/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", app.redeliverWebhookHandler)
+		})
+
+		// Search
+		r.Get("/v1/search", app.searchHandler)
 
 		// Store
 		r.Post("/v1/store", app.createStoreHandler)
@@ -25,6 +64,44 @@ func (app *application) routes() *chi.Mux {
 		r.Get("/v1/store-options", app.getStoreOptions)
 		r.Get("/v1/store-id", app.getStoreId)
 
+		// Trash
+		r.Get("/v1/trash/stores", app.listDeletedStoresHandler)
+		r.With(app.RequireStoreId).Post("/v1/trash/stores/{store_id}/restore", app.restoreStoreHandler)
+
+		// Store by name
+		r.Group(func(r chi.Router) {
+			r.Use(app.RequireStoreName)
+
+			r.Get("/v1/stores/by-name/{store_name}", app.getStoreHandler)
+			r.Delete("/v1/stores/by-name/{store_name}", app.deleteStoreHandler)
+			r.Post("/v1/stores/by-name/{store_name}/items", app.createItemsHandler)
+			r.Get("/v1/stores/by-name/{store_name}/items", app.listItemsHandler)
+			r.Put("/v1/stores/by-name/{store_name}/items", app.updateItemsHandler)
+		})
+
+		// Item by name
+		r.Group(func(r chi.Router) {
+			r.Use(app.RequireItemName)
+
+			r.Get("/v1/stores/by-name/{store_name}/items/by-name/{item_name}", app.getItemsHandler)
+			r.Delete("/v1/stores/by-name/{store_name}/items/by-name/{item_name}", app.deleteItemsHandler)
+			r.Get("/v1/stores/by-name/{store_name}/items/by-name/{item_name}/eaten", app.getEatenHandler)
+			r.Post("/v1/stores/by-name/{store_name}/items/by-name/{item_name}/eaten", app.createEatenHandler)
+		})
+
+		// Meal plan
+		r.Post("/v1/meal-plan", app.createMealPlanSlotHandler)
+		r.Get("/v1/meal-plan", app.listMealPlanHandler)
+		r.Put("/v1/meal-plan", app.updateMealPlanSlotHandler)
+
+		// Meal plan slot by ID
+		r.Group(func(r chi.Router) {
+			r.Use(app.RequireSlotId)
+
+			r.Get("/v1/meal-plan/{slot_id}", app.getMealPlanSlotHandler)
+			r.Delete("/v1/meal-plan/{slot_id}", app.deleteMealPlanSlotHandler)
+		})
+
 		// Store by ID
 		r.Group(func(r chi.Router) {
 			r.Use(app.RequireStoreId)
@@ -36,12 +113,19 @@ func (app *application) routes() *chi.Mux {
 			r.Post("/v1/store/{store_id}/eatenItem", app.createEatenHandler)
 			r.Post("/v1/store/{store_id}/eatenItem-list", app.createEatenListHandler)
 
+			// Trash
+			r.Get("/v1/store/{store_id}/trash", app.listDeletedItemsHandler)
+			r.With(app.RequireItemId).Post("/v1/store/{store_id}/trash/{item_id}/restore", app.restoreItemHandler)
+
 			// Items
 			r.Post("/v1/store/{store_id}/items", app.createItemsHandler)
 			r.Post("/v1/store/{store_id}/items-list", app.createItemsListHandler)
 			r.Get("/v1/store/{store_id}/items", app.listItemsHandler)
 			r.Get("/v1/store/{store_id}/items-options", app.getItemsOptionsHandler)
 			r.Get("/v1/store/{store_id}/item-id", app.getItemsId)
+			r.Get("/v1/store/{store_id}/items-stale", app.listStaleItemsHandler)
+			r.Get("/v1/store/{store_id}/items-forecast", app.forecastItemsHandler)
+			r.Post("/v1/store/{store_id}/parse", app.parseTextHandler)
 			r.Put("/v1/store/{store_id}/items", app.updateItemsHandler)
 			r.Put("/v1/store/{store_id}/items-list", app.updateItemsListHandler)
 
@@ -51,10 +135,57 @@ func (app *application) routes() *chi.Mux {
 
 				r.Get("/v1/store/{store_id}/items/{item_id}", app.getItemsHandler)
 				r.Delete("/v1/store/{store_id}/items/{item_id}", app.deleteItemsHandler)
+				r.Post("/v1/store/{store_id}/items/{item_id}/merge", app.mergeItemsHandler)
+				r.Post("/v1/store/{store_id}/items/{item_id}/transfer", app.transferItemsHandler)
 
 				r.Get("/v1/store/{store_id}/eatenItem/{item_id}", app.getEatenHandler)
 
 			})
+
+			// Recipes
+			r.Post("/v1/store/{store_id}/recipes", app.createRecipeHandler)
+			r.Get("/v1/store/{store_id}/recipes", app.listRecipesHandler)
+			r.Put("/v1/store/{store_id}/recipes", app.updateRecipeHandler)
+			r.Post("/v1/store/{store_id}/recipes-check", app.checkRecipeHandler)
+
+			// Shopping lists
+			r.Post("/v1/store/{store_id}/shopping-lists/generate", app.generateShoppingListHandler)
+			r.Get("/v1/store/{store_id}/shopping-lists", app.listShoppingListsHandler)
+
+			// Shopping list by ID
+			r.Group(func(r chi.Router) {
+				r.Use(app.RequireListId)
+
+				r.Get("/v1/store/{store_id}/shopping-lists/{list_id}", app.getShoppingListHandler)
+				r.Delete("/v1/store/{store_id}/shopping-lists/{list_id}", app.deleteShoppingListHandler)
+
+				r.With(app.RequireEntryId).Put("/v1/store/{store_id}/shopping-lists/{list_id}/entries/{entry_id}/check", app.checkShoppingListEntryHandler)
+			})
+
+			// Meal plan stock projection
+			r.Get("/v1/store/{store_id}/meal-plan-projection", app.mealPlanProjectionHandler)
+
+			// Recipe by ID
+			r.Group(func(r chi.Router) {
+				r.Use(app.RequireRecipeId)
+
+				r.Get("/v1/store/{store_id}/recipes/{recipe_id}", app.getRecipeHandler)
+				r.Delete("/v1/store/{store_id}/recipes/{recipe_id}", app.deleteRecipeHandler)
+			})
+
+			// Meal presets
+			r.Post("/v1/store/{store_id}/presets", app.createPresetHandler)
+			r.Get("/v1/store/{store_id}/presets", app.listPresetsHandler)
+			r.Put("/v1/store/{store_id}/presets", app.updatePresetHandler)
+
+			// Meal preset by ID
+			r.Group(func(r chi.Router) {
+				r.Use(app.RequirePresetId)
+
+				r.Get("/v1/store/{store_id}/presets/{preset_id}", app.getPresetHandler)
+				r.Delete("/v1/store/{store_id}/presets/{preset_id}", app.deletePresetHandler)
+				r.Post("/v1/store/{store_id}/presets/{preset_id}/log", app.logPresetHandler)
+			})
 		})
 	})
 
@@ -0,0 +1,66 @@
+package main
+
+import (
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// searchHandler finds items and stores across all of the user's stores by
+// an approximate name.
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	searchReq := struct {
+		Q     string `validate:"required,min=2,max=100"`
+		Limit int    `validate:"gte=1,lte=50"`
+	}{
+		Q:     strings.TrimSpace(r.URL.Query().Get("q")),
+		Limit: 20,
+	}
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		searchReq.Limit = parsed
+	}
+
+	v := newValidator()
+	err := v.Struct(searchReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	results, err := app.models.Search.Search(r.Context(), userId, searchReq.Q, searchReq.Limit)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"query": searchReq.Q, "results": results})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"io"
+	"net/http"
+	"time"
+)
+
+func (app *application) generateShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	var generateReq struct {
+		Name   *string `json:"name,omitempty"`
+		Target int     `json:"target,omitempty" validate:"omitempty,gte=1"`
+	}
+
+	err := app.readeJSON(r, &generateReq)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(generateReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	if generateReq.Target == 0 {
+		generateReq.Target = DefaultTargetCapacity
+	}
+
+	if generateReq.Name == nil {
+		name := fmt.Sprintf("Shopping %s", time.Now().Format("2006-01-02"))
+		generateReq.Name = &name
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	lowItems, err := app.models.Items.List(r.Context(), storeId, true)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if len(lowItems) == 0 {
+		app.WriteErrorCode(w, r, http.StatusBadRequest, CodeNothingLow, "no items are below the warning threshold")
+		return
+	}
+
+	list := ShoppingList{Name: generateReq.Name, StoreId: storeId}
+
+	err = app.models.Shopping.Generate(r.Context(), &list, lowItems, generateReq.Target)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelop{"shopping_list": list})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listShoppingListsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	lists, err := app.models.Shopping.List(r.Context(), storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"shopping_lists": lists})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) getShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	listId, ok := r.Context().Value(ListIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	list, err := app.models.Shopping.Get(r.Context(), listId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"shopping_list": list})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) checkShoppingListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var checkReq struct {
+		Quantity *int `json:"quantity,omitempty" validate:"omitempty,gte=1"`
+	}
+
+	err := app.readeJSON(r, &checkReq)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(checkReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	listId, ok := r.Context().Value(ListIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	entryId, ok := r.Context().Value(EntryIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err = app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	item, err := app.models.Shopping.Check(r.Context(), entryId, listId, storeId, checkReq.Quantity)
+
+	if errors.Is(err, ErrEntryChecked) {
+		app.WriteErrorCode(w, r, http.StatusConflict, CodeEntryChecked, err.Error())
+		return
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	app.emit(r.Context(), userId, storeId, EventItemUpdated, item)
+	app.checkLowStock(userId, storeId, *item.Id)
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"checked_entry_id": entryId, "restocked_item": item})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) deleteShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	listId, ok := r.Context().Value(ListIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.models.Shopping.Delete(r.Context(), listId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_shopping_list_id": listId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
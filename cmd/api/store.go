@@ -2,7 +2,7 @@ package main
 
 import (
 	. "github.com/Piccio-Code/MealStore/internal/data"
-	"github.com/go-playground/validator/v10"
+	"github.com/Piccio-Code/MealStore/internal/filters"
 	"net/http"
 )
 
@@ -17,7 +17,7 @@ func (app *application) createStoreHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newStore)
 
 	if err != nil {
@@ -35,8 +35,7 @@ func (app *application) createStoreHandler(w http.ResponseWriter, r *http.Reques
 	err = app.models.Stores.Insert(r.Context(), &newStore, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -59,15 +58,21 @@ func (app *application) listStoreHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	stores, err := app.models.Stores.List(r.Context(), userId)
+	pageFilters, err := filters.New(r.URL.Query(), "name", "created_at", "modified_at")
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	stores, metadata, err := app.models.Stores.ListPage(r.Context(), userId, pageFilters)
+
+	if err != nil {
+		app.DBError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelop{"stores": stores})
+	err = app.writeJSON(w, http.StatusOK, envelop{"stores": stores, "metadata": metadata})
 
 	if err != nil {
 		app.errorLog.Println(err)
@@ -94,8 +99,11 @@ func (app *application) getStoreHandler(w http.ResponseWriter, r *http.Request)
 	store, err := app.models.Stores.Get(r.Context(), storeId, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
+		return
+	}
+
+	if app.notModified(w, r, store.Version) {
 		return
 	}
 
@@ -119,8 +127,7 @@ func (app *application) getStoreOptions(w http.ResponseWriter, r *http.Request)
 	stores, err := app.models.Stores.List(r.Context(), userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -152,8 +159,7 @@ func (app *application) getStoreId(w http.ResponseWriter, r *http.Request) {
 	storeId, err := app.models.Stores.GetID(r.Context(), storeName, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.DBError(w, r, err)
 		return
 	}
 
@@ -177,7 +183,7 @@ func (app *application) updateStoreHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v := validator.New(validator.WithRequiredStructEnabled())
+	v := newValidator()
 	err = v.Struct(newStore)
 
 	if err != nil {
@@ -192,14 +198,41 @@ func (app *application) updateStoreHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// If-Match takes the place of the version in the body.
+	if hasIfMatch(r) {
+		if newStore.ID == nil {
+			app.WriteError(w, r, http.StatusBadRequest, "id is required")
+			return
+		}
+
+		oldStore, err := app.models.Stores.Get(r.Context(), *newStore.ID, userId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		if app.preconditionFailed(w, r, oldStore.Version) {
+			return
+		}
+
+		newStore.Version = oldStore.Version
+	}
+
+	if newStore.Version == nil {
+		app.WriteErrorCode(w, r, http.StatusPreconditionRequired, CodeVersionRequired, "send the version in the body or in an If-Match header")
+		return
+	}
+
 	err = app.models.Stores.Update(r.Context(), &newStore, userId)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.conditionalError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(newStore.Version))
+
 	err = app.writeJSON(w, http.StatusOK, envelop{"updated_store": newStore})
 
 	if err != nil {
@@ -224,14 +257,32 @@ func (app *application) deleteStoreHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := app.models.Stores.Delete(r.Context(), storeId, userId)
+	var version *string
+
+	if hasIfMatch(r) {
+		store, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+
+		if app.preconditionFailed(w, r, store.Version) {
+			return
+		}
+
+		version = store.Version
+	}
+
+	err := app.models.Stores.Delete(r.Context(), storeId, userId, version)
 
 	if err != nil {
-		app.errorLog.Println(err)
-		app.BadRequestError(w, r)
+		app.conditionalError(w, r, err)
 		return
 	}
 
+	app.emit(r.Context(), userId, storeId, EventStoreDeleted, envelop{"store_id": storeId})
+
 	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_store_id": storeId})
 
 	if err != nil {
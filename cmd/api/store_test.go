@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/testdb"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestApp returns an application on a fresh database with silent logs.
+func newTestApp(t *testing.T) *application {
+	t.Helper()
+
+	pool := testdb.New(t)
+
+	return &application{
+		infoLog:  log.New(io.Discard, "", 0),
+		errorLog: log.New(io.Discard, "", 0),
+		pool:     pool,
+		models:   NewModels(pool),
+	}
+}
+
+func TestUpdateStoreNameClash(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	var stores []Store
+
+	for _, name := range []string{"Latte", "Dispensa"} {
+		store := Store{Name: &name}
+
+		if err := app.models.Stores.Insert(ctx, &store, "user"); err != nil {
+			t.Fatal(err)
+		}
+
+		stores = append(stores, store)
+	}
+
+	body := fmt.Sprintf(`{"id": %d, "name": "latte ", "version": %q}`, *stores[1].ID, *stores[1].Version)
+	r := httptest.NewRequest(http.MethodPatch, "/stores", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), CurrentUserIDKey, "user"))
+	w := httptest.NewRecorder()
+
+	app.updateStoreHandler(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+
+	var resp struct {
+		Error ErrorResponse `json:"error"`
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	conflict := resp.Error.Conflict
+
+	if conflict == nil || conflict.ExistingId != *stores[0].ID || conflict.ExistingName != "Latte" {
+		t.Errorf("got conflict %+v, want store %d named Latte", conflict, *stores[0].ID)
+	}
+}
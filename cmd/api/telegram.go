@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Piccio-Code/MealStore/internal/bot"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"github.com/Piccio-Code/MealStore/internal/fuzzy"
+	"github.com/Piccio-Code/MealStore/internal/notify"
+	"net/http"
+	"strings"
+)
+
+const botHelp = `Commands:
+/stores - your stores
+/items <store> - the items in a store
+/eat <item> [qty] [@store] - log what you ate
+/add <item> [qty] [@store] - restock an item or create it
+/low [store] - the items running low`
+
+// Telegram caps callback data at 64 bytes and large keyboards get unwieldy.
+const (
+	botCallbackLimit = 64
+	botKeyboardLimit = 30
+)
+
+type botMatch struct {
+	store Store
+	item  Item
+}
+
+// telegramWebhookHandler receives updates from the Telegram Bot API. It
+// answers 200 to everything it has authenticated, even failed commands,
+// since Telegram would otherwise keep redelivering the update.
+func (app *application) telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+
+	if app.config.telegramSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(app.config.telegramSecret)) != 1 {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	var update bot.Update
+
+	// Updates carry many fields we don't model, so readeJSON can't be used.
+	err := json.NewDecoder(r.Body).Decode(&update)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	chatId, text, ok := update.ChatId()
+
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if chatId != allowedChatId {
+		app.infoLog.Printf("telegram: ignoring chat %s", chatId)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if telegram, ok := app.notifier.(*notify.Telegram); ok && update.CallbackQuery != nil {
+		err = telegram.AnswerCallback(r.Context(), update.CallbackQuery.Id)
+
+		if err != nil {
+			app.errorLog.Println(err)
+		}
+	}
+
+	reply := app.runBotCommand(r.Context(), chatId, text)
+	reply.ChatId = chatId
+
+	err = app.notifier.Send(r.Context(), reply)
+
+	if err != nil {
+		app.errorLog.Println(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (app *application) runBotCommand(ctx context.Context, userId, text string) notify.Message {
+	cmd, ok := bot.ParseCommand(text)
+
+	if !ok {
+		return notify.Message{Text: botHelp}
+	}
+
+	var reply notify.Message
+	var err error
+
+	switch cmd.Name {
+	case "stores":
+		reply, err = app.botStores(ctx, userId)
+	case "items":
+		reply, err = app.botItems(ctx, userId, cmd.Args)
+	case "eat":
+		reply, err = app.botEat(ctx, userId, cmd.Args)
+	case "add":
+		reply, err = app.botAdd(ctx, userId, cmd.Args)
+	case "low":
+		reply, err = app.botLow(ctx, userId, cmd.Args)
+	default:
+		reply = notify.Message{Text: botHelp}
+	}
+
+	if err != nil {
+		app.errorLog.Println(err)
+		return notify.Message{Text: "Something went wrong, please try again."}
+	}
+
+	return reply
+}
+
+// botStore finds a store by "#id" or by name. A missing store is reported
+// with ok false rather than an error.
+func (app *application) botStore(ctx context.Context, userId, arg string) (store Store, ok bool, err error) {
+	storeId, isRef := bot.Ref(arg)
+
+	if !isRef {
+		storeId, err = app.models.Stores.GetID(ctx, arg, userId)
+	}
+
+	if err == nil {
+		store, err = app.models.Stores.Get(ctx, storeId, userId)
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		return Store{}, false, nil
+	}
+
+	return store, err == nil, err
+}
+
+// botStores lists the user's stores with a button to open each of them.
+func (app *application) botStores(ctx context.Context, userId string) (notify.Message, error) {
+	stores, err := app.models.Stores.List(ctx, userId)
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	if len(stores) == 0 {
+		return notify.Message{Text: "You have no stores yet."}, nil
+	}
+
+	var lines []string
+	var keyboard [][]notify.Button
+
+	for _, store := range stores {
+		lines = append(lines, *store.Name)
+		keyboard = append(keyboard, []notify.Button{{Text: *store.Name, Data: fmt.Sprintf("/items #%d", *store.ID)}})
+	}
+
+	return notify.Message{Text: "Your stores:\n" + strings.Join(lines, "\n"), Keyboard: keyboard}, nil
+}
+
+func (app *application) botItems(ctx context.Context, userId, args string) (notify.Message, error) {
+	if args == "" {
+		return app.botStores(ctx, userId)
+	}
+
+	store, ok, err := app.botStore(ctx, userId, args)
+
+	if err != nil || !ok {
+		return notify.Message{Text: fmt.Sprintf("I can't find the store %q.", args)}, err
+	}
+
+	items, err := app.models.Items.List(ctx, *store.ID, false)
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	if len(items) == 0 {
+		return notify.Message{Text: fmt.Sprintf("%s is empty.", *store.Name)}, nil
+	}
+
+	var lines []string
+	var keyboard [][]notify.Button
+
+	for i, item := range items {
+		lines = append(lines, itemOption(item))
+
+		if i < botKeyboardLimit {
+			keyboard = append(keyboard, []notify.Button{{Text: "Eat 1 " + *item.Name, Data: fmt.Sprintf("/eat #%d 1 @#%d", *item.Id, *store.ID)}})
+		}
+	}
+
+	return notify.Message{Text: *store.Name + ":\n" + strings.Join(lines, "\n"), Keyboard: keyboard}, nil
+}
+
+// botFind looks the target item up in the given store, or in every store of
+// the user. It returns all the candidates and the stores that were searched.
+func (app *application) botFind(ctx context.Context, userId string, target bot.Target) (matches []botMatch, stores []Store, err error) {
+	if target.Store != "" {
+		store, ok, err := app.botStore(ctx, userId, target.Store)
+
+		if err != nil || !ok {
+			return nil, nil, err
+		}
+
+		stores = []Store{store}
+	} else {
+		stores, err = app.models.Stores.List(ctx, userId)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	itemId, isRef := bot.Ref(target.Item)
+
+	for _, store := range stores {
+		items, err := app.models.Items.List(ctx, *store.ID, false)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var names []string
+
+		for _, item := range items {
+			if isRef && *item.Id == itemId {
+				matches = append(matches, botMatch{store: store, item: item})
+			}
+
+			names = append(names, *item.Name)
+		}
+
+		if isRef {
+			continue
+		}
+
+		if index, _ := fuzzy.Best(target.Item, names, 0.8); index >= 0 {
+			matches = append(matches, botMatch{store: store, item: items[index]})
+		}
+	}
+
+	return matches, stores, nil
+}
+
+// botChoose asks which of several matching items the command was meant for.
+func botChoose(command string, quantity int, matches []botMatch) notify.Message {
+	var keyboard [][]notify.Button
+
+	for _, match := range matches {
+		keyboard = append(keyboard, []notify.Button{{
+			Text: fmt.Sprintf("%s in %s", *match.item.Name, *match.store.Name),
+			Data: fmt.Sprintf("/%s #%d %d @#%d", command, *match.item.Id, quantity, *match.store.ID),
+		}})
+	}
+
+	return notify.Message{Text: "Which one?", Keyboard: keyboard}
+}
+
+func (app *application) botEat(ctx context.Context, userId, args string) (notify.Message, error) {
+	target, ok := bot.ParseTarget(args)
+
+	if !ok {
+		return notify.Message{Text: "Usage: /eat <item> [qty] [@store]"}, nil
+	}
+
+	matches, _, err := app.botFind(ctx, userId, target)
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return notify.Message{Text: fmt.Sprintf("I can't find %q.", target.Item)}, nil
+	case 1:
+	default:
+		return botChoose("eat", target.Quantity, matches), nil
+	}
+
+	match := matches[0]
+	eatenItem := &EatenItem{Quantity: target.Quantity, ItemId: *match.item.Id}
+
+	err = app.models.EatenItems.CreateList(ctx, []*EatenItem{eatenItem})
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	app.emit(ctx, userId, *match.store.ID, EventEatenCreated, eatenItem)
+
+	return notify.Message{Text: fmt.Sprintf("Logged %d × %s from %s.", target.Quantity, *match.item.Name, *match.store.Name)}, nil
+}
+
+func (app *application) botAdd(ctx context.Context, userId, args string) (notify.Message, error) {
+	target, ok := bot.ParseTarget(args)
+
+	if !ok {
+		return notify.Message{Text: "Usage: /add <item> [qty] [@store]"}, nil
+	}
+
+	matches, stores, err := app.botFind(ctx, userId, target)
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	if len(matches) > 1 {
+		return botChoose("add", target.Quantity, matches), nil
+	}
+
+	if len(matches) == 1 {
+		match := matches[0]
+		capacity := *match.item.CurrentCapacity + target.Quantity
+		match.item.CurrentCapacity = &capacity
+
+		err = app.models.Items.Update(ctx, &match.item)
+
+		if err != nil {
+			return notify.Message{}, err
+		}
+
+		app.emit(ctx, userId, *match.store.ID, EventItemUpdated, match.item)
+		app.checkLowStock(userId, *match.store.ID, *match.item.Id)
+
+		return notify.Message{Text: fmt.Sprintf("%s in %s: now %d.", *match.item.Name, *match.store.Name, capacity)}, nil
+	}
+
+	if _, isRef := bot.Ref(target.Item); isRef || len(stores) == 0 {
+		return notify.Message{Text: fmt.Sprintf("I can't find %q.", target.Item)}, nil
+	}
+
+	if len(stores) > 1 {
+		var keyboard [][]notify.Button
+
+		for _, store := range stores {
+			data := fmt.Sprintf("/add %s %d @#%d", target.Item, target.Quantity, *store.ID)
+
+			if len(data) > botCallbackLimit {
+				return notify.Message{Text: "Which store? Add @store at the end of the command."}, nil
+			}
+
+			keyboard = append(keyboard, []notify.Button{{Text: *store.Name, Data: data}})
+		}
+
+		return notify.Message{Text: fmt.Sprintf("%q is new, which store should it go in?", target.Item), Keyboard: keyboard}, nil
+	}
+
+	store := stores[0]
+	newItem := Item{Name: &target.Item, CurrentCapacity: &target.Quantity, StoreId: *store.ID}
+
+	err = app.models.Items.Insert(ctx, &newItem)
+
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	app.emit(ctx, userId, *store.ID, EventItemCreated, newItem)
+
+	return notify.Message{Text: fmt.Sprintf("Added %d × %s to %s.", target.Quantity, target.Item, *store.Name)}, nil
+}
+
+func (app *application) botLow(ctx context.Context, userId, args string) (notify.Message, error) {
+	var stores []Store
+
+	if args != "" {
+		store, ok, err := app.botStore(ctx, userId, args)
+
+		if err != nil || !ok {
+			return notify.Message{Text: fmt.Sprintf("I can't find the store %q.", args)}, err
+		}
+
+		stores = []Store{store}
+	} else {
+		var err error
+
+		stores, err = app.models.Stores.List(ctx, userId)
+
+		if err != nil {
+			return notify.Message{}, err
+		}
+	}
+
+	var lines []string
+	var keyboard [][]notify.Button
+
+	for _, store := range stores {
+		items, err := app.models.Items.List(ctx, *store.ID, true)
+
+		if err != nil {
+			return notify.Message{}, err
+		}
+
+		for _, item := range items {
+			lines = append(lines, fmt.Sprintf("%s in %s", itemOption(item), *store.Name))
+
+			if len(keyboard) < botKeyboardLimit {
+				keyboard = append(keyboard, []notify.Button{{Text: "Restock " + *item.Name, Data: fmt.Sprintf("/add #%d 1 @#%d", *item.Id, *store.ID)}})
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return notify.Message{Text: "Nothing is running low."}, nil
+	}
+
+	return notify.Message{Text: "Running low:\n" + strings.Join(lines, "\n"), Keyboard: keyboard}, nil
+}
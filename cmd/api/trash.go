@@ -0,0 +1,185 @@
+package main
+
+import (
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"net/http"
+	"time"
+)
+
+// purgeAt is when a row deleted at deletedAt leaves the trash for good.
+func (app *application) purgeAt(deletedAt *time.Time) *time.Time {
+	if deletedAt == nil {
+		return nil
+	}
+
+	at := deletedAt.Add(app.config.trashRetention)
+
+	return &at
+}
+
+type trashedStore struct {
+	Store
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+type trashedItem struct {
+	Item
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+func (app *application) listDeletedStoresHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	stores, err := app.models.Stores.ListDeleted(r.Context(), userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	trash := make([]trashedStore, 0, len(stores))
+
+	for _, store := range stores {
+		trash = append(trash, trashedStore{Store: store, PurgeAt: app.purgeAt(store.DeletedAt)})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"stores": trash})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) restoreStoreHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	store, err := app.models.Stores.Restore(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(store.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"restored_store": store})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listDeletedItemsHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	items, err := app.models.Items.ListDeleted(r.Context(), storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	trash := make([]trashedItem, 0, len(items))
+
+	for _, item := range items {
+		trash = append(trash, trashedItem{Item: item, PurgeAt: app.purgeAt(item.DeletedAt)})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"items": trash})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) restoreItemHandler(w http.ResponseWriter, r *http.Request) {
+	storeId, ok := r.Context().Value(StoreIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	itemId, ok := r.Context().Value(ItemIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Stores.Get(r.Context(), storeId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	item, err := app.models.Items.Restore(r.Context(), itemId, storeId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(item.Version))
+
+	app.emit(r.Context(), userId, storeId, EventItemUpdated, item)
+	app.checkLowStock(userId, storeId, *item.Id)
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"restored_item": item})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	. "github.com/Piccio-Code/MealStore/internal/data"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type webhookPayload struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	StoreId    int         `json:"store_id"`
+	Data       interface{} `json:"data"`
+}
+
+// emit queues the event for the user's webhook subscriptions. Failing to
+// queue it is logged but never fails the request that caused it.
+func (app *application) emit(ctx context.Context, userId string, storeId int, event string, data interface{}) {
+	payload, err := json.Marshal(webhookPayload{
+		Event:      event,
+		OccurredAt: time.Now().UTC(),
+		StoreId:    storeId,
+		Data:       data,
+	})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		return
+	}
+
+	err = app.models.Webhooks.Enqueue(context.WithoutCancel(ctx), userId, storeId, event, payload)
+
+	if err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var newWebhook Webhook
+
+	err := app.readeJSON(r, &newWebhook)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newWebhook)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	if newWebhook.StoreId != nil {
+		_, err = app.models.Stores.Get(r.Context(), *newWebhook.StoreId, userId)
+
+		if err != nil {
+			app.DBError(w, r, err)
+			return
+		}
+	}
+
+	if newWebhook.Secret == nil {
+		secret, err := newWebhookSecret()
+
+		if err != nil {
+			app.errorLog.Println(err)
+			app.InternalServerError(w, r)
+			return
+		}
+
+		newWebhook.Secret = &secret
+	}
+
+	newWebhook.UserId = userId
+
+	err = app.models.Webhooks.Insert(r.Context(), &newWebhook)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	// The secret is only ever shown here.
+	err = app.writeJSON(w, http.StatusCreated, envelop{"new_webhook": newWebhook})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	webhooks, err := app.models.Webhooks.List(r.Context(), userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"webhooks": webhooks})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := r.Context().Value(WebhookIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	webhook, err := app.models.Webhooks.Get(r.Context(), webhookId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"webhook": webhook})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var newWebhookReq UpdateWebhook
+
+	err := app.readeJSON(r, &newWebhookReq)
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+
+	v := newValidator()
+	err = v.Struct(newWebhookReq)
+
+	if err != nil {
+		app.ValidationError(w, r, err)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	oldWebhook, err := app.models.Webhooks.Get(r.Context(), *newWebhookReq.Id, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	if newWebhookReq.URL == nil {
+		newWebhookReq.URL = oldWebhook.URL
+	}
+
+	if newWebhookReq.Events == nil {
+		newWebhookReq.Events = oldWebhook.Events
+	}
+
+	if newWebhookReq.Active == nil {
+		newWebhookReq.Active = oldWebhook.Active
+	}
+
+	newWebhook := Webhook{
+		Id:      newWebhookReq.Id,
+		UserId:  userId,
+		StoreId: oldWebhook.StoreId,
+		URL:     newWebhookReq.URL,
+		Secret:  newWebhookReq.Secret,
+		Events:  newWebhookReq.Events,
+		Active:  newWebhookReq.Active,
+		Version: newWebhookReq.Version,
+	}
+
+	err = app.models.Webhooks.Update(r.Context(), &newWebhook)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	newWebhook.Secret = nil
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"updated_webhook": newWebhook})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := r.Context().Value(WebhookIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	err := app.models.Webhooks.Delete(r.Context(), webhookId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deleted_webhook_id": webhookId})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := r.Context().Value(WebhookIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Webhooks.Get(r.Context(), webhookId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	limit := 20
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+
+		if err != nil || parsed < 1 || parsed > 100 {
+			app.errorLog.Println(err)
+			app.BadRequestError(w, r)
+			return
+		}
+
+		limit = parsed
+	}
+
+	deliveries, err := app.models.Webhooks.ListDeliveries(r.Context(), webhookId, limit)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelop{"deliveries": deliveries})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
+
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := r.Context().Value(WebhookIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	deliveryId, ok := r.Context().Value(DeliveryIdKey).(int)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	userId, ok := r.Context().Value(CurrentUserIDKey).(string)
+
+	if !ok {
+		app.UnauthorizedError(w, r)
+		return
+	}
+
+	_, err := app.models.Webhooks.Get(r.Context(), webhookId, userId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	delivery, err := app.models.Webhooks.Redeliver(r.Context(), deliveryId, webhookId)
+
+	if err != nil {
+		app.DBError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, envelop{"delivery": delivery})
+
+	if err != nil {
+		app.errorLog.Println(err)
+		app.BadRequestError(w, r)
+		return
+	}
+}
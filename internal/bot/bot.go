@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Update is the subset of a Telegram Bot API update the bot reacts to:
+// plain messages and inline keyboard presses.
+type Update struct {
+	UpdateId      int            `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+type Message struct {
+	MessageId int    `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type Chat struct {
+	Id int64 `json:"id"`
+}
+
+type CallbackQuery struct {
+	Id      string   `json:"id"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data"`
+}
+
+// ChatId returns the chat the update came from and the text to run, which
+// for a keyboard press is the button data.
+func (u Update) ChatId() (chatId string, text string, ok bool) {
+	switch {
+	case u.Message != nil:
+		return strconv.FormatInt(u.Message.Chat.Id, 10), u.Message.Text, true
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		return strconv.FormatInt(u.CallbackQuery.Message.Chat.Id, 10), u.CallbackQuery.Data, true
+	}
+
+	return "", "", false
+}
+
+type Command struct {
+	Name string
+	Args string
+}
+
+// ParseCommand splits "/eat@MealStoreBot pasta 2" into its name and
+// arguments. Text that is not a command is reported as such.
+func ParseCommand(text string) (Command, bool) {
+	text = strings.TrimSpace(text)
+
+	if !strings.HasPrefix(text, "/") {
+		return Command{}, false
+	}
+
+	name, args, _ := strings.Cut(text[1:], " ")
+	name, _, _ = strings.Cut(name, "@")
+
+	if name == "" {
+		return Command{}, false
+	}
+
+	return Command{Name: strings.ToLower(name), Args: strings.TrimSpace(args)}, true
+}
+
+// Target is the "<item> [qty] [@store]" argument of /eat and /add.
+type Target struct {
+	Item     string
+	Quantity int
+	Store    string
+}
+
+// ParseTarget reads "<item> [qty] [@store]". The store is everything after
+// the last " @" so that names with spaces work; the quantity defaults to 1.
+func ParseTarget(args string) (Target, bool) {
+	t := Target{Quantity: 1}
+
+	args = strings.TrimSpace(args)
+
+	if i := strings.LastIndex(" "+args, " @"); i >= 0 {
+		t.Store = strings.TrimSpace(args[i+1:])
+		args = strings.TrimSpace(args[:i])
+	}
+
+	fields := strings.Fields(args)
+
+	if len(fields) > 1 {
+		if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			if n < 1 {
+				return Target{}, false
+			}
+
+			t.Quantity = n
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	t.Item = strings.Join(fields, " ")
+
+	return t, t.Item != ""
+}
+
+// Ref reads a "#42" reference to a row by id, as used in keyboard buttons.
+func Ref(arg string) (int, bool) {
+	if !strings.HasPrefix(arg, "#") {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(arg[1:])
+
+	return id, err == nil && id > 0
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want Command
+		ok   bool
+	}{
+		{"/eat pasta 2", Command{Name: "eat", Args: "pasta 2"}, true},
+		{"/Eat@MealStoreBot  pasta 2 ", Command{Name: "eat", Args: "pasta 2"}, true},
+		{"  /list", Command{Name: "list"}, true},
+		{"/", Command{}, false},
+		{"/@MealStoreBot", Command{}, false},
+		{"ate 2 eggs", Command{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := ParseCommand(tt.text)
+
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ParseCommand(%q) = %+v, %v, want %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		args string
+		want Target
+		ok   bool
+	}{
+		{"pasta", Target{Item: "pasta", Quantity: 1}, true},
+		{"pasta 2", Target{Item: "pasta", Quantity: 2}, true},
+		{"olio di oliva 3", Target{Item: "olio di oliva", Quantity: 3}, true},
+		{"pasta 2 @Casa al mare", Target{Item: "pasta", Quantity: 2, Store: "Casa al mare"}, true},
+		{"pasta @casa", Target{Item: "pasta", Quantity: 1, Store: "casa"}, true},
+		{"pasta @casa @mare", Target{Item: "pasta @casa", Quantity: 1, Store: "mare"}, true},
+		{"user@example.com", Target{Item: "user@example.com", Quantity: 1}, true},
+		{"7up", Target{Item: "7up", Quantity: 1}, true},
+		{"2", Target{Item: "2", Quantity: 1}, true},
+		{"pasta 0", Target{}, false},
+		{"pasta -1", Target{}, false},
+		{"@casa", Target{Quantity: 1, Store: "casa"}, false},
+		{"", Target{Quantity: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, ok := ParseTarget(tt.args)
+
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ParseTarget(%q) = %+v, %v, want %+v, %v", tt.args, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRef(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want int
+		ok   bool
+	}{
+		{"#42", 42, true},
+		{"#0", 0, false},
+		{"#x", 0, false},
+		{"42", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, ok := Ref(tt.arg)
+
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("Ref(%q) = %d, %v, want %d, %v", tt.arg, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUpdateChatId(t *testing.T) {
+	message := &Message{Chat: Chat{Id: -100123}, Text: "/list"}
+
+	tests := []struct {
+		name   string
+		update Update
+		chatId string
+		text   string
+		ok     bool
+	}{
+		{"message", Update{Message: message}, "-100123", "/list", true},
+		{"keyboard press", Update{CallbackQuery: &CallbackQuery{Message: message, Data: "/eat #4"}}, "-100123", "/eat #4", true},
+		{"press without message", Update{CallbackQuery: &CallbackQuery{Data: "/eat #4"}}, "", "", false},
+		{"nothing", Update{}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatId, text, ok := tt.update.ChatId()
+
+			if chatId != tt.chatId || text != tt.text || ok != tt.ok {
+				t.Errorf("ChatId() = %q, %q, %v, want %q, %q, %v", chatId, text, ok, tt.chatId, tt.text, tt.ok)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"net/url"
 	"time"
@@ -66,16 +67,21 @@ func (e EatenItemsModel) Create(ctx context.Context, item EatenItem) error {
 
 	args := []interface{}{item.Quantity, item.ItemId}
 
-	_, err := e.DB.Exec(ctx, stmt, args)
+	_, err := e.DB.Exec(ctx, stmt, args...)
 
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	return nil
 }
 
+// CreateList copies the entries into a temporary table and inserts them
+// from there in one statement. Ids and dates are set on the entries.
 func (e EatenItemsModel) CreateList(ctx context.Context, items []*EatenItem) error {
+	if len(items) == 0 {
+		return nil
+	}
 
 	tx, err := e.DB.Begin(ctx)
 
@@ -85,20 +91,60 @@ func (e EatenItemsModel) CreateList(ctx context.Context, items []*EatenItem) err
 
 	defer tx.Rollback(ctx)
 
-	for _, item := range items {
-		stmt := `
-			INSERT INTO eatenitems(quantity, item_id) 
-			VALUES ($1, $2)
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE eatenitems_import (ord INT, quantity INT, item_id INT) ON COMMIT DROP`)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"eatenitems_import"}, []string{"ord", "quantity", "item_id"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			return []any{i, items[i].Quantity, items[i].ItemId}, nil
+		}))
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	stmt := `
+			WITH ids AS (
+				SELECT ord, nextval(pg_get_serial_sequence('eatenitems', 'id')) AS id
+				FROM eatenitems_import
+			), inserted AS (
+				INSERT INTO eatenitems(id, quantity, item_id)
+				SELECT ids.id, i.quantity, i.item_id
+				FROM eatenitems_import i JOIN ids USING (ord)
+				ORDER BY i.ord
+				RETURNING id, eaten_date
+			)
+			SELECT ids.ord, inserted.id, inserted.eaten_date
+			FROM inserted JOIN ids USING (id)
 	`
 
-		args := []interface{}{item.Quantity, item.ItemId}
+	rows, err := tx.Query(ctx, stmt)
+
+	if err != nil {
+		return dbError(err)
+	}
 
-		_, err := tx.Exec(ctx, stmt, args...)
+	defer rows.Close()
+
+	for rows.Next() {
+		var ord int
+		var item EatenItem
+
+		err = rows.Scan(&ord, &item.Id, &item.EatenDate)
 
 		if err != nil {
 			return err
 		}
 
+		items[ord].Id = item.Id
+		items[ord].EatenDate = item.EatenDate
+	}
+
+	if err = rows.Err(); err != nil {
+		return dbError(err)
 	}
 
 	return tx.Commit(ctx)
@@ -109,7 +155,7 @@ func (e EatenItemsModel) Get(ctx context.Context, itemId int, filters EatenItemF
 	stmt := `
 		SELECT e.id, quantity, eaten_date, i.name
 		FROM eatenitems e 
-			JOIN public.items i on i.id = e.item_id
+			JOIN public.items i on i.id = e.item_id AND i.deleted_at IS NULL
 		WHERE item_id = $1 AND eaten_date >= $2
 	`
 
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+// Models return these instead of driver errors so that callers can tell a
+// missing row from a clash without knowing about Postgres.
+var (
+	ErrNotFound     = errors.New("record not found")
+	ErrDuplicate    = errors.New("a record with the same name already exists")
+	ErrEditConflict = errors.New("edit conflict: the record was changed or deleted, fetch it again")
+	ErrForeignKey   = errors.New("a referenced record does not exist")
+)
+
+// Postgres SQLSTATE codes, see the "PostgreSQL Error Codes" appendix.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// dbError translates a driver error into the errors above. The constraint
+// name is kept in the message; anything else is returned as it is.
+func dbError(err error) error {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+		return fmt.Errorf("%w (%s)", ErrDuplicate, pgErr.ConstraintName)
+	case errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation:
+		return fmt.Errorf("%w (%s)", ErrForeignKey, pgErr.ConstraintName)
+	}
+
+	return err
+}
+
+// updateError is dbError for the "UPDATE ... WHERE id = $1 AND version = $2
+// RETURNING ..." statements, where no row back means someone else saved
+// first or the row is gone.
+func updateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEditConflict
+	}
+
+	return dbError(err)
+}
+
+// DuplicateNameError is returned when a name is the same as the one of
+// another record once both are normalized, e.g. "Latte" and "latte ". It
+// unwraps to ErrDuplicate.
+type DuplicateNameError struct {
+	Name         string `json:"name"`
+	ExistingId   int    `json:"existing_id,omitempty"`
+	ExistingName string `json:"existing_name,omitempty"`
+}
+
+func (e *DuplicateNameError) Error() string {
+	if e.ExistingId == 0 {
+		return fmt.Sprintf("%q is given more than once", e.Name)
+	}
+
+	return fmt.Sprintf("%q is already taken by %q (id %d)", e.Name, e.ExistingName, e.ExistingId)
+}
+
+func (e *DuplicateNameError) Unwrap() error {
+	return ErrDuplicate
+}
+
+// ItemReference is a recipe, preset or shopping list using an item.
+type ItemReference struct {
+	Kind string `json:"kind"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ItemInUseError is returned when an item can't leave its store because
+// records of the store still use it.
+type ItemInUseError struct {
+	ItemId     int
+	References []ItemReference
+}
+
+func (e *ItemInUseError) Error() string {
+	names := make([]string, 0, len(e.References))
+
+	for _, ref := range e.References {
+		names = append(names, fmt.Sprintf("%s %q", ref.Kind, ref.Name))
+	}
+
+	return fmt.Sprintf("item %d is still used by %s", e.ItemId, strings.Join(names, ", "))
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// nameClash turns a duplicate error from writing names into table into a
+// DuplicateNameError that says which record is in the way. The records are
+// those with scopeColumn = scope other than exceptIds, which are the ones
+// being written. Other errors are returned as they are.
+func nameClash(ctx context.Context, q queryRower, err error, table, scopeColumn string, scope any, exceptIds []int, names ...string) error {
+	if !errors.Is(err, ErrDuplicate) {
+		return err
+	}
+
+	var clash DuplicateNameError
+
+	stmt := fmt.Sprintf(`
+			SELECT n, t.id, t.name
+			FROM unnest($2::text[]) n
+				JOIN %s t ON t.%s = $1 AND t.name_norm = normalize_name(n)
+			WHERE t.id <> ALL(COALESCE($3::int[], '{}')) AND t.deleted_at IS NULL
+			LIMIT 1
+	`, table, scopeColumn)
+
+	if q.QueryRow(ctx, stmt, scope, names, exceptIds).Scan(&clash.Name, &clash.ExistingId, &clash.ExistingName) == nil {
+		return &clash
+	}
+
+	// Otherwise the names clash among themselves.
+	stmt = `
+			SELECT min(n)
+			FROM unnest($1::text[]) n
+			GROUP BY normalize_name(n)
+			HAVING count(*) > 1
+			LIMIT 1
+	`
+
+	if q.QueryRow(ctx, stmt, names).Scan(&clash.Name) == nil {
+		return &clash
+	}
+
+	return err
+}
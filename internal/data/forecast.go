@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type Forecast struct {
+	ItemId          int        `json:"item_id"`
+	ItemName        string     `json:"item_name"`
+	CurrentCapacity int        `json:"current_capacity"`
+	DailyRate       float64    `json:"daily_rate"`
+	DaysRemaining   *float64   `json:"days_remaining"`
+	RunOutDate      *time.Time `json:"run_out_date"`
+}
+
+type ForecastFilters struct {
+	Alpha  float64 `validate:"gt=0,lte=1"`
+	Window int     `validate:"gte=7,lte=365"`
+}
+
+// NewForecastFilters reads the smoothing factor (alpha) and the number of
+// days of history (window) used by the forecast.
+func NewForecastFilters(query url.Values) (filters ForecastFilters, err error) {
+	filters.Alpha = 0.3
+	filters.Window = 90
+
+	if val := query.Get("alpha"); val != "" {
+		filters.Alpha, err = strconv.ParseFloat(val, 64)
+
+		if err != nil {
+			return ForecastFilters{}, err
+		}
+	}
+
+	if val := query.Get("window"); val != "" {
+		filters.Window, err = strconv.Atoi(val)
+
+		if err != nil {
+			return ForecastFilters{}, err
+		}
+	}
+
+	v := validator.New()
+	err = v.Struct(filters)
+
+	if err != nil {
+		return ForecastFilters{}, err
+	}
+
+	return filters, nil
+}
+
+// EWMA returns the exponentially weighted moving average of series, giving
+// weight alpha to each new value. An empty series averages to 0.
+func EWMA(series []float64, alpha float64) float64 {
+	if len(series) == 0 {
+		return 0
+	}
+
+	avg := series[0]
+
+	for _, x := range series[1:] {
+		avg = alpha*x + (1-alpha)*avg
+	}
+
+	return avg
+}
+
+// SortByDaysRemaining orders forecasts from the item that runs out first;
+// items that are not being consumed go last.
+func SortByDaysRemaining(forecasts []Forecast) {
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		a, b := forecasts[i].DaysRemaining, forecasts[j].DaysRemaining
+
+		if a == nil || b == nil {
+			return a != nil
+		}
+
+		return *a < *b
+	})
+}
+
+// Forecast estimates the daily consumption of every item of the store from
+// its eaten history, starting from the first day it was eaten within the
+// window, and predicts when its current capacity runs out.
+func (e EatenItemsModel) Forecast(ctx context.Context, storeId int, filters ForecastFilters) (forecasts []Forecast, err error) {
+	tx, err := e.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT i.id, i.name, i.current_capacity, (current_date - e.eaten_date::date) AS days_ago, SUM(e.quantity)
+			FROM items i
+				LEFT JOIN eatenitems e ON e.item_id = i.id AND e.eaten_date >= current_date - $2::int
+			WHERE i.store_id = $1 AND i.deleted_at IS NULL
+			GROUP BY i.id, i.name, i.current_capacity, days_ago
+			ORDER BY i.id, days_ago DESC
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId, filters.Window)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var series []float64
+
+	flush := func() {
+		if len(forecasts) == 0 {
+			return
+		}
+
+		f := &forecasts[len(forecasts)-1]
+		f.DailyRate = math.Round(EWMA(series, filters.Alpha)*1000) / 1000
+
+		if f.DailyRate > 0 {
+			days := math.Round(float64(f.CurrentCapacity)/f.DailyRate*10) / 10
+			runOut := time.Now().Add(time.Duration(days * 24 * float64(time.Hour)))
+
+			f.DaysRemaining = &days
+			f.RunOutDate = &runOut
+		}
+	}
+
+	for rows.Next() {
+		var f Forecast
+		var daysAgo, quantity *int
+
+		err := rows.Scan(&f.ItemId, &f.ItemName, &f.CurrentCapacity, &daysAgo, &quantity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(forecasts) == 0 || forecasts[len(forecasts)-1].ItemId != f.ItemId {
+			flush()
+
+			forecasts = append(forecasts, f)
+			series = nil
+		}
+
+		if daysAgo == nil {
+			continue
+		}
+
+		// Rows come oldest first; pad the days nobody ate the item with zeros.
+		if series == nil {
+			series = make([]float64, *daysAgo+1)
+		}
+
+		series[len(series)-1-*daysAgo] += float64(*quantity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return forecasts, tx.Commit(ctx)
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type IdempotencyModel struct {
+	DB *pgxpool.Pool
+}
+
+// IdempotentRequest is what is kept of the first request sent with a key.
+// StatusCode is nil while that request is still being handled. Headers are
+// the response headers worth replaying.
+type IdempotentRequest struct {
+	UserId      string
+	Key         string
+	RequestHash string
+	StatusCode  *int
+	Body        []byte
+	Headers     map[string][]string
+	ExpiresAt   time.Time
+}
+
+// Begin claims the key for a request. ok is true when the caller owns it
+// and must Complete or Release it; otherwise existing is the request that
+// got there first. Expired keys are claimed again.
+func (m IdempotencyModel) Begin(ctx context.Context, userId, key, requestHash string, ttl time.Duration) (existing IdempotentRequest, ok bool, err error) {
+	stmt := `
+			INSERT INTO idempotency_keys(user_id, key, request_hash, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status_code = NULL, body = NULL, headers = NULL,
+			    created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < NOW()
+			RETURNING user_id
+	`
+
+	var owner string
+
+	err = m.DB.QueryRow(ctx, stmt, userId, key, requestHash, time.Now().Add(ttl)).Scan(&owner)
+
+	if err == nil {
+		return IdempotentRequest{}, true, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return IdempotentRequest{}, false, err
+	}
+
+	stmt = `
+			SELECT user_id, key, request_hash, status_code, body, headers, expires_at
+			FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2
+	`
+
+	err = m.DB.QueryRow(ctx, stmt, userId, key).Scan(&existing.UserId, &existing.Key, &existing.RequestHash, &existing.StatusCode, &existing.Body, &existing.Headers, &existing.ExpiresAt)
+
+	return existing, false, dbError(err)
+}
+
+// Complete stores the response so that retries get it back.
+func (m IdempotencyModel) Complete(ctx context.Context, userId, key string, statusCode int, headers map[string][]string, body []byte) error {
+	stmt := `
+			UPDATE idempotency_keys
+			SET status_code = $3, headers = $4, body = $5
+			WHERE user_id = $1 AND key = $2
+	`
+
+	_, err := m.DB.Exec(ctx, stmt, userId, key, statusCode, headers, body)
+
+	return err
+}
+
+// Release frees a key whose request failed so that it can be retried.
+func (m IdempotencyModel) Release(ctx context.Context, userId, key string) error {
+	stmt := `
+			DELETE FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+	`
+
+	_, err := m.DB.Exec(ctx, stmt, userId, key)
+
+	return err
+}
+
+// Cleanup deletes the expired keys.
+func (m IdempotencyModel) Cleanup(ctx context.Context) (int64, error) {
+	result, err := m.DB.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -3,10 +3,18 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/Piccio-Code/MealStore/internal/filters"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"strings"
 	"time"
 )
 
+// WarningThreshold is the capacity at or below which an item is reported
+// as running low.
+const WarningThreshold = 1
+
 type ItemModel struct {
 	DB *pgxpool.Pool
 }
@@ -19,13 +27,14 @@ type Item struct {
 	Version         *string    `json:"version"`
 	CreatedAt       *time.Time `json:"created_at"`
 	ModifiedAt      *time.Time `json:"modified_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
 }
 
 type UpdateItem struct {
 	Id              *int    `json:"id,omitempty" validate:"required"`
 	Name            *string `json:"name,omitempty"`
-	CurrentCapacity *int    `json:"current_capacity,omitempty" validate:"gte=1"`
-	Version         *string `json:"version" validate:"required"`
+	CurrentCapacity *int    `json:"current_capacity,omitempty" validate:"omitempty,gte=1"`
+	Version         *string `json:"version"`
 }
 
 func (m ItemModel) List(ctx context.Context, storeId int, onlyWarnings bool) (items []Item, err error) {
@@ -40,10 +49,10 @@ func (m ItemModel) List(ctx context.Context, storeId int, onlyWarnings bool) (it
 	stmt := `
 			SELECT id, name, current_capacity, store_id, version, created_at, modified_at
 			FROM items
-			WHERE store_id = $1 AND (current_capacity <= 1 OR NOT $2)
+			WHERE store_id = $1 AND deleted_at IS NULL AND (current_capacity <= $3 OR NOT $2)
 	`
 
-	rows, err := tx.Query(ctx, stmt, storeId, onlyWarnings)
+	rows, err := tx.Query(ctx, stmt, storeId, onlyWarnings, WarningThreshold)
 
 	if err != nil {
 		return nil, err
@@ -70,6 +79,122 @@ func (m ItemModel) List(ctx context.Context, storeId int, onlyWarnings bool) (it
 	return items, tx.Commit(ctx)
 }
 
+// ItemSortColumns are the fields items can be sorted by. Items that are
+// not being eaten never run out, so they sort as if they had infinite
+// days remaining.
+var ItemSortColumns = map[string]filters.Column{
+	"id":               {Expr: "id", Type: "int"},
+	"name":             {Expr: "name", Type: "text"},
+	"current_capacity": {Expr: "current_capacity", Type: "int"},
+	"created_at":       {Expr: "created_at", Type: "timestamp"},
+	"modified_at":      {Expr: "COALESCE(modified_at, created_at)", Type: "timestamp"},
+	"days_remaining":   {Expr: "COALESCE(days_remaining, 'Infinity')", Type: "float8"},
+}
+
+// ListedItem is an item as listed, with the days its stock is forecast to
+// last; nil when it is not being eaten.
+type ListedItem struct {
+	Item
+	DaysRemaining *float64 `json:"days_remaining"`
+}
+
+// ListPage is List one page at a time, with the capacity kept within
+// capacity. Days remaining are forecast as in Forecast, in SQL so that
+// they can be sorted and paginated by.
+func (m ItemModel) ListPage(ctx context.Context, storeId int, onlyWarnings bool, capacity filters.Range, forecast ForecastFilters, f filters.Filters) (items []ListedItem, metadata filters.Metadata, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	conditions := `
+			WHERE store_id = $1 AND deleted_at IS NULL AND (current_capacity <= $3 OR NOT $2)
+				AND ($4::int IS NULL OR current_capacity >= $4)
+				AND ($5::int IS NULL OR current_capacity <= $5)
+	`
+
+	args := []any{storeId, onlyWarnings, WarningThreshold, capacity.Min, capacity.Max}
+
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM items`+conditions, args...).Scan(&metadata.TotalRecords)
+
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	// The EWMA of the daily totals, oldest day first, in closed form: the
+	// oldest day weighs (1-alpha)^days_ago, every later one
+	// alpha*(1-alpha)^days_ago. Rounded as Forecast rounds it.
+	forecasted := `
+			(
+				SELECT i.*, CASE WHEN r.rate > 0 THEN round(i.current_capacity / r.rate, 1)::float8 END AS days_remaining
+				FROM items i
+					LEFT JOIN LATERAL (
+						SELECT round(SUM(d.quantity * CASE
+								WHEN d.days_ago = d.first_day THEN power(1 - $6::float8, d.days_ago)
+								ELSE $6::float8 * power(1 - $6::float8, d.days_ago)
+							END)::numeric, 3) AS rate
+						FROM (
+							SELECT days_ago, quantity, max(days_ago) OVER () AS first_day
+							FROM (
+								SELECT current_date - e.eaten_date::date AS days_ago, SUM(e.quantity) AS quantity
+								FROM eatenitems e
+								WHERE e.item_id = i.id AND e.eaten_date >= current_date - $7::int
+								GROUP BY days_ago
+							) daily
+						) d
+					) r ON TRUE
+			) items`
+
+	args = append(args, forecast.Alpha, forecast.Window)
+
+	sortKey, where, orderLimit, pageArgs := f.Keyset(ItemSortColumns, len(args)+1)
+
+	stmt := `SELECT id, name, current_capacity, store_id, version, created_at, modified_at, days_remaining, ` + sortKey + `
+			FROM ` + forecasted + conditions + ` AND ` + where + `
+			` + orderLimit
+
+	rows, err := tx.Query(ctx, stmt, append(args, pageArgs...)...)
+
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	var sortKeys []string
+
+	for rows.Next() {
+		var item ListedItem
+		var key string
+
+		err := rows.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt, &item.DaysRemaining, &key)
+
+		if err != nil {
+			return nil, filters.Metadata{}, err
+		}
+
+		items = append(items, item)
+		sortKeys = append(sortKeys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	fetched := len(items)
+	metadata.PageSize = f.PageSize
+
+	if fetched > f.PageSize {
+		items = items[:f.PageSize]
+		metadata.NextCursor = f.NextCursor(fetched, sortKeys[f.PageSize-1], *items[f.PageSize-1].Id)
+	}
+
+	return items, metadata, tx.Commit(ctx)
+}
+
 func (m ItemModel) Insert(ctx context.Context, newItem *Item) error {
 	tx, err := m.DB.Begin(ctx)
 
@@ -90,13 +215,20 @@ func (m ItemModel) Insert(ctx context.Context, newItem *Item) error {
 	err = tx.QueryRow(ctx, stmt, args...).Scan(&newItem.Id, &newItem.Version, &newItem.CreatedAt)
 
 	if err != nil {
-		return err
+		return nameClash(ctx, m.DB, dbError(err), "items", "store_id", newItem.StoreId, nil, *newItem.Name)
 	}
 
 	return tx.Commit(ctx)
 }
 
+// InsertList copies the items into a temporary table and inserts them from
+// there in one statement, so a list costs the same few round trips whatever
+// its length. Ids, versions and creation times are set on the items.
 func (m ItemModel) InsertList(ctx context.Context, newItemList []*Item, storeId int) error {
+	if len(newItemList) == 0 {
+		return nil
+	}
+
 	tx, err := m.DB.Begin(ctx)
 
 	if err != nil {
@@ -105,25 +237,78 @@ func (m ItemModel) InsertList(ctx context.Context, newItemList []*Item, storeId
 
 	defer tx.Rollback(ctx)
 
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE items_import (ord INT, name VARCHAR(255), current_capacity INT) ON COMMIT DROP`)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"items_import"}, []string{"ord", "name", "current_capacity"},
+		pgx.CopyFromSlice(len(newItemList), func(i int) ([]any, error) {
+			return []any{i, *newItemList[i].Name, *newItemList[i].CurrentCapacity}, nil
+		}))
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	// The ids are drawn before inserting so that each row returned can be
+	// matched back to its place in the list.
 	stmt := `
-			INSERT INTO items(name, current_capacity, store_id)
-			VALUES ($1, $2, $3)
-			RETURNING id, version, created_at
+			WITH ids AS (
+				SELECT ord, nextval(pg_get_serial_sequence('items', 'id')) AS id
+				FROM items_import
+			), inserted AS (
+				INSERT INTO items(id, name, current_capacity, store_id)
+				SELECT ids.id, i.name, i.current_capacity, $1
+				FROM items_import i JOIN ids USING (ord)
+				ORDER BY i.ord
+				RETURNING id, version, created_at
+			)
+			SELECT ids.ord, inserted.id, inserted.version, inserted.created_at
+			FROM inserted JOIN ids USING (id)
 	`
 
-	for _, newItem := range newItemList {
-		args := []interface{}{*newItem.Name, *newItem.CurrentCapacity, storeId}
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return m.insertListError(ctx, storeId, newItemList, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ord int
+		var newItem Item
 
-		err = tx.QueryRow(ctx, stmt, args...).Scan(&newItem.Id, &newItem.Version, &newItem.CreatedAt)
+		err = rows.Scan(&ord, &newItem.Id, &newItem.Version, &newItem.CreatedAt)
 
 		if err != nil {
 			return err
 		}
+
+		newItemList[ord].Id = newItem.Id
+		newItemList[ord].Version = newItem.Version
+		newItemList[ord].CreatedAt = newItem.CreatedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return m.insertListError(ctx, storeId, newItemList, err)
 	}
 
 	return tx.Commit(ctx)
 }
 
+func (m ItemModel) insertListError(ctx context.Context, storeId int, newItemList []*Item, err error) error {
+	names := make([]string, len(newItemList))
+
+	for i, newItem := range newItemList {
+		names[i] = *newItem.Name
+	}
+
+	return nameClash(ctx, m.DB, dbError(err), "items", "store_id", storeId, nil, names...)
+}
+
 func (m ItemModel) Get(ctx context.Context, itemId, storeId int) (item Item, err error) {
 	tx, err := m.DB.Begin(ctx)
 
@@ -136,18 +321,20 @@ func (m ItemModel) Get(ctx context.Context, itemId, storeId int) (item Item, err
 	stmt := `
 			SELECT id, name, current_capacity, store_id, version, created_at, modified_at
 			FROM items
-			WHERE id = $1 AND store_id = $2
+			WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL
 	`
 
 	err = tx.QueryRow(ctx, stmt, itemId, storeId).Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
 
 	if err != nil {
-		return Item{}, err
+		return Item{}, dbError(err)
 	}
 
 	return item, tx.Commit(ctx)
 }
 
+// GetId finds an item by name the way names are kept unique: regardless of
+// case and extra spaces.
 func (m ItemModel) GetId(ctx context.Context, itemName string, storeId int) (itemId int, err error) {
 	tx, err := m.DB.Begin(ctx)
 
@@ -160,18 +347,38 @@ func (m ItemModel) GetId(ctx context.Context, itemName string, storeId int) (ite
 	stmt := `
 			SELECT id
 			FROM items
-			WHERE name = $1 AND store_id = $2
+			WHERE name_norm = normalize_name($1) AND store_id = $2 AND deleted_at IS NULL
 	`
 
 	err = tx.QueryRow(ctx, stmt, itemName, storeId).Scan(&itemId)
 
 	if err != nil {
-		return 0, err
+		return 0, dbError(err)
 	}
 
 	return itemId, tx.Commit(ctx)
 }
 
+// GetIdByNames finds the store and the item of the user in one query. Both
+// names are matched like in GetId.
+func (m ItemModel) GetIdByNames(ctx context.Context, storeName, itemName, userId string) (storeId, itemId int, err error) {
+	stmt := `
+			SELECT s.id, i.id
+			FROM stores s
+				JOIN items i ON i.store_id = s.id
+			WHERE s.name_norm = normalize_name($1) AND i.name_norm = normalize_name($2) AND s.user_id = $3
+				AND s.deleted_at IS NULL AND i.deleted_at IS NULL
+	`
+
+	err = m.DB.QueryRow(ctx, stmt, storeName, itemName, userId).Scan(&storeId, &itemId)
+
+	if err != nil {
+		return 0, 0, dbError(err)
+	}
+
+	return storeId, itemId, nil
+}
+
 func (m ItemModel) Update(ctx context.Context, item *Item) error {
 	tx, err := m.DB.Begin(ctx)
 	if err != nil {
@@ -182,7 +389,7 @@ func (m ItemModel) Update(ctx context.Context, item *Item) error {
 	stmt := `
 			UPDATE items
 			SET name = $1, current_capacity = $2, modified_at = now(), version = uuid_generate_v4()
-			WHERE id = $3 AND version = $4 AND store_id = $5
+			WHERE id = $3 AND version = $4 AND store_id = $5 AND deleted_at IS NULL
 			RETURNING version, modified_at, created_at
 `
 
@@ -191,27 +398,692 @@ func (m ItemModel) Update(ctx context.Context, item *Item) error {
 	err = tx.QueryRow(ctx, stmt, args...).Scan(&item.Version, &item.ModifiedAt, &item.CreatedAt)
 
 	if err != nil {
-		return err
+		return nameClash(ctx, m.DB, updateError(err), "items", "store_id", item.StoreId, []int{*item.Id}, *item.Name)
 	}
 
 	return tx.Commit(ctx)
 }
 
-func (m ItemModel) Delete(ctx context.Context, itemId, storeId int) error {
+// ItemUpdateResult is the outcome of one entry of UpdateList.
+type ItemUpdateResult struct {
+	Item Item
+	Err  error
+}
+
+const updateListStmt = `
+			UPDATE items
+			SET name = COALESCE($1, name), current_capacity = COALESCE($2, current_capacity),
+			    modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $3 AND version = $4 AND store_id = $5 AND deleted_at IS NULL
+			RETURNING id, name, current_capacity, store_id, version, created_at, modified_at
+`
+
+// UpdateList applies the updates in one transaction. A nil name or capacity
+// keeps the current one. Unless partial, the first entry that fails rolls
+// back the whole list and its error, prefixed with its index, is returned.
+// With partial every entry is tried and the failed ones are reported in
+// their result.
+func (m ItemModel) UpdateList(ctx context.Context, storeId int, updates []*UpdateItem, partial bool) ([]ItemUpdateResult, error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	results := make([]ItemUpdateResult, len(updates))
+
+	if partial {
+		for i, update := range updates {
+			results[i].Item, results[i].Err = m.updateOne(ctx, tx, storeId, update)
+		}
+
+		return results, tx.Commit(ctx)
+	}
+
+	batch := &pgx.Batch{}
+
+	for _, update := range updates {
+		batch.Queue(updateListStmt, update.Name, update.CurrentCapacity, update.Id, update.Version, storeId)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	failed := -1
+
+	for i := range updates {
+		item := &results[i].Item
+
+		err = br.QueryRow().Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
+
+		if err != nil {
+			failed = i
+			break
+		}
+	}
+
+	closeErr := br.Close()
+
+	if failed >= 0 {
+		err = m.updateListError(ctx, tx, storeId, *updates[failed].Id, err)
+
+		// The list is rolled back, so the clash is looked for outside of it.
+		var ids []int
+		var names []string
+
+		for _, update := range updates {
+			ids = append(ids, *update.Id)
+
+			if update.Name != nil {
+				names = append(names, *update.Name)
+			}
+		}
+
+		return nil, fmt.Errorf("items[%d]: %w", failed, nameClash(ctx, m.DB, err, "items", "store_id", storeId, ids, names...))
+	}
+
+	if closeErr != nil {
+		return nil, closeErr
+	}
+
+	return results, tx.Commit(ctx)
+}
+
+// updateOne runs a single update of a partial list in its own savepoint, so
+// that a failing entry doesn't abort the others.
+func (m ItemModel) updateOne(ctx context.Context, tx pgx.Tx, storeId int, update *UpdateItem) (item Item, err error) {
+	sp, err := tx.Begin(ctx)
+
+	if err != nil {
+		return Item{}, err
+	}
+
+	err = sp.QueryRow(ctx, updateListStmt, update.Name, update.CurrentCapacity, update.Id, update.Version, storeId).Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
+
+	if err != nil {
+		_ = sp.Rollback(ctx)
+		err = m.updateListError(ctx, tx, storeId, *update.Id, err)
+
+		if update.Name != nil {
+			err = nameClash(ctx, tx, err, "items", "store_id", storeId, []int{*update.Id}, *update.Name)
+		}
+
+		return Item{}, err
+	}
+
+	return item, sp.Commit(ctx)
+}
+
+// updateListError tells an item that doesn't exist from one whose version
+// moved on when an update matched no row.
+func (m ItemModel) updateListError(ctx context.Context, tx pgx.Tx, storeId, itemId int, err error) error {
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return dbError(err)
+	}
+
+	var exists bool
+
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM items WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL)`, itemId, storeId).Scan(&exists)
+
+	if err != nil || exists {
+		return ErrEditConflict
+	}
+
+	return ErrNotFound
+}
+
+// MergeItems is the body of a merge: the item folded into the target and
+// the versions both are expected to be at.
+type MergeItems struct {
+	SourceId      *int    `json:"source_id" validate:"required"`
+	SourceVersion *string `json:"source_version" validate:"required"`
+	Version       *string `json:"version"`
+}
+
+// Merge folds source into target in one transaction: the capacities are
+// added up, everything that pointed at source (eaten entries, recipe
+// ingredients, meal plans, shopping lists and presets) points at target
+// and source is deleted. Either version being stale is an ErrEditConflict.
+func (m ItemModel) Merge(ctx context.Context, storeId, targetId int, targetVersion string, sourceId int, sourceVersion string) (item Item, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return Item{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
 	stmt := `
-			DELETE FROM items
-			WHERE id = $1 AND store_id = $2
+			SELECT id, version::text, current_capacity
+			FROM items
+			WHERE id IN ($1, $2) AND store_id = $3 AND deleted_at IS NULL
+			ORDER BY id
+			FOR UPDATE
 	`
 
-	result, err := m.DB.Exec(ctx, stmt, itemId, storeId)
+	rows, err := tx.Query(ctx, stmt, targetId, sourceId, storeId)
 
 	if err != nil {
-		return err
+		return Item{}, err
+	}
+
+	versions := make(map[int]string)
+	var sourceCapacity int
+
+	for rows.Next() {
+		var id, capacity int
+		var version string
+
+		err = rows.Scan(&id, &version, &capacity)
+
+		if err != nil {
+			rows.Close()
+			return Item{}, err
+		}
+
+		versions[id] = version
+
+		if id == sourceId {
+			sourceCapacity = capacity
+		}
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return Item{}, err
+	}
+
+	if len(versions) != 2 {
+		return Item{}, ErrNotFound
+	}
+
+	if !strings.EqualFold(versions[targetId], targetVersion) || !strings.EqualFold(versions[sourceId], sourceVersion) {
+		return Item{}, ErrEditConflict
+	}
+
+	err = repointItem(ctx, tx, targetId, sourceId)
+
+	if err != nil {
+		return Item{}, err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM items WHERE id = $1`, sourceId)
+
+	if err != nil {
+		return Item{}, dbError(err)
+	}
+
+	stmt = `
+			UPDATE items
+			SET current_capacity = current_capacity + $1, modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $2
+			RETURNING id, name, current_capacity, store_id, version, created_at, modified_at
+	`
+
+	err = tx.QueryRow(ctx, stmt, sourceCapacity, targetId).Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
+
+	if err != nil {
+		return Item{}, dbError(err)
+	}
+
+	return item, tx.Commit(ctx)
+}
+
+// repointItem makes everything that refers to source (eaten entries,
+// recipe ingredients, meal plans, shopping lists and presets) refer to
+// target instead.
+func repointItem(ctx context.Context, tx pgx.Tx, targetId, sourceId int) error {
+	stmts := []string{
+		`UPDATE eatenitems SET item_id = $1 WHERE item_id = $2`,
+
+		// A recipe can hold an ingredient only once, so where it has both
+		// the quantities are added up.
+		`UPDATE recipe_ingredients t
+		 SET quantity = t.quantity + s.quantity
+		 FROM recipe_ingredients s
+		 WHERE t.item_id = $1 AND s.item_id = $2 AND s.recipe_id = t.recipe_id`,
+		`DELETE FROM recipe_ingredients s
+		 WHERE s.item_id = $2 AND EXISTS (
+			SELECT 1 FROM recipe_ingredients t WHERE t.recipe_id = s.recipe_id AND t.item_id = $1
+		 )`,
+		`UPDATE recipe_ingredients SET item_id = $1 WHERE item_id = $2`,
+
+		`UPDATE meal_plan_items SET item_id = $1 WHERE item_id = $2`,
+		`UPDATE shopping_list_entries SET item_id = $1 WHERE item_id = $2`,
+		`UPDATE meal_preset_entries SET item_id = $1 WHERE item_id = $2`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(ctx, stmt, targetId, sourceId)
+
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	return nil
+}
+
+// itemReferences lists the recipes, presets and shopping lists using the
+// item. Meal plans and eaten entries belong to the user rather than to a
+// store and are left out.
+func itemReferences(ctx context.Context, tx pgx.Tx, itemId int) (refs []ItemReference, err error) {
+	stmt := `
+			SELECT DISTINCT 'recipe' AS kind, r.id, r.name
+			FROM recipe_ingredients ri
+				JOIN recipes r ON r.id = ri.recipe_id
+			WHERE ri.item_id = $1
+			UNION
+			SELECT 'preset', p.id, p.name
+			FROM meal_preset_entries pe
+				JOIN meal_presets p ON p.id = pe.preset_id
+			WHERE pe.item_id = $1
+			UNION
+			SELECT 'shopping_list', l.id, l.name
+			FROM shopping_list_entries le
+				JOIN shopping_lists l ON l.id = le.list_id
+			WHERE le.item_id = $1
+			ORDER BY kind, name, id
+	`
+
+	rows, err := tx.Query(ctx, stmt, itemId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref ItemReference
+
+		err := rows.Scan(&ref.Kind, &ref.Id, &ref.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, rows.Err()
+}
+
+var ErrNotEnoughStock = errors.New("not enough stock to transfer")
+
+// TransferItem is the body of a transfer. Without a quantity the whole
+// item moves, history included. TargetName renames it in the target store.
+type TransferItem struct {
+	TargetStoreId *int    `json:"target_store_id" validate:"required"`
+	Quantity      *int    `json:"quantity" validate:"omitempty,gte=1"`
+	TargetName    *string `json:"target_name" validate:"omitempty,min=1,max=255"`
+	Version       *string `json:"version"`
+}
+
+type TransferResult struct {
+	// Source is nil when the whole item left its store.
+	Source *Item `json:"source,omitempty"`
+	Target Item  `json:"target"`
+
+	// Created is set when the target item is new, Merged when the whole
+	// item was folded into one already in the target store.
+	Created bool `json:"created"`
+	Merged  bool `json:"merged"`
+}
+
+// Transfer moves stock from the item to another store in one transaction.
+// The name is looked up in the target store the way names are kept unique:
+// a quantity is added to the item found there, or to a new one, and a
+// whole item is merged into it or moved across as it is. A whole item
+// still used by recipes, presets or shopping lists, which belong to its
+// store, stays where it is and an ItemInUseError lists them.
+func (m ItemModel) Transfer(ctx context.Context, storeId, itemId int, version string, transfer TransferItem) (result TransferResult, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var name, currentVersion string
+	var capacity int
+
+	stmt := `
+			SELECT name, version::text, current_capacity
+			FROM items
+			WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL
+			FOR UPDATE
+	`
+
+	err = tx.QueryRow(ctx, stmt, itemId, storeId).Scan(&name, &currentVersion, &capacity)
+
+	if err != nil {
+		return TransferResult{}, dbError(err)
+	}
+
+	if !strings.EqualFold(currentVersion, version) {
+		return TransferResult{}, ErrEditConflict
+	}
+
+	if transfer.Quantity == nil {
+		refs, err := itemReferences(ctx, tx, itemId)
+
+		if err != nil {
+			return TransferResult{}, err
+		}
+
+		if len(refs) > 0 {
+			return TransferResult{}, &ItemInUseError{ItemId: itemId, References: refs}
+		}
+	}
+
+	if transfer.TargetName != nil {
+		name = *transfer.TargetName
+	}
+
+	var existingId *int
+
+	stmt = `
+			SELECT id
+			FROM items
+			WHERE store_id = $1 AND name_norm = normalize_name($2) AND deleted_at IS NULL
+			FOR UPDATE
+	`
+
+	err = tx.QueryRow(ctx, stmt, *transfer.TargetStoreId, name).Scan(&existingId)
+
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return TransferResult{}, err
+	}
+
+	returning := ` RETURNING id, name, current_capacity, store_id, version, created_at, modified_at`
+
+	scan := func(row pgx.Row, item *Item) error {
+		return dbError(row.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt))
+	}
+
+	quantity := capacity
+
+	if transfer.Quantity != nil {
+		quantity = *transfer.Quantity
+
+		if quantity > capacity {
+			return TransferResult{}, fmt.Errorf("%w: %d left", ErrNotEnoughStock, capacity)
+		}
+
+		stmt = `
+				UPDATE items
+				SET current_capacity = current_capacity - $1, modified_at = now(), version = uuid_generate_v4()
+				WHERE id = $2` + returning
+
+		result.Source = &Item{}
+
+		err = scan(tx.QueryRow(ctx, stmt, quantity, itemId), result.Source)
+
+		if err != nil {
+			return TransferResult{}, err
+		}
+	}
+
+	switch {
+	case existingId != nil:
+		if transfer.Quantity == nil {
+			err = repointItem(ctx, tx, *existingId, itemId)
+
+			if err != nil {
+				return TransferResult{}, err
+			}
+
+			_, err = tx.Exec(ctx, `DELETE FROM items WHERE id = $1`, itemId)
+
+			if err != nil {
+				return TransferResult{}, dbError(err)
+			}
+
+			result.Merged = true
+		}
+
+		stmt = `
+				UPDATE items
+				SET current_capacity = current_capacity + $1, modified_at = now(), version = uuid_generate_v4()
+				WHERE id = $2` + returning
+
+		err = scan(tx.QueryRow(ctx, stmt, quantity, *existingId), &result.Target)
+	case transfer.Quantity == nil:
+		// The history follows the item, which keeps its id.
+		stmt = `
+				UPDATE items
+				SET store_id = $1, name = $2, modified_at = now(), version = uuid_generate_v4()
+				WHERE id = $3` + returning
+
+		err = scan(tx.QueryRow(ctx, stmt, *transfer.TargetStoreId, name, itemId), &result.Target)
+	default:
+		stmt = `
+				INSERT INTO items(name, current_capacity, store_id)
+				VALUES ($1, $2, $3)` + returning
+
+		err = scan(tx.QueryRow(ctx, stmt, name, quantity, *transfer.TargetStoreId), &result.Target)
+		result.Created = true
+	}
+
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	return result, tx.Commit(ctx)
+}
+
+// Delete moves the item to the trash, with its history, until Restore or
+// Purge. With a version it only does so if the item is still at that
+// version and returns ErrEditConflict otherwise.
+func (m ItemModel) Delete(ctx context.Context, itemId, storeId int, version *string) error {
+	stmt := `
+			UPDATE items
+			SET deleted_at = now(), version = uuid_generate_v4()
+			WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL AND ($3::uuid IS NULL OR version = $3::uuid)
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, itemId, storeId, version)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 && version != nil {
+		return ErrEditConflict
 	}
 
 	if result.RowsAffected() == 0 {
-		return errors.New("0 effected rows")
+		return ErrNotFound
 	}
 
 	return err
 }
+
+// ListDeleted returns the items of the store in the trash, most recently
+// deleted first.
+func (m ItemModel) ListDeleted(ctx context.Context, storeId int) (items []Item, err error) {
+	stmt := `
+			SELECT id, name, current_capacity, store_id, version, created_at, modified_at, deleted_at
+			FROM items
+			WHERE store_id = $1 AND deleted_at IS NOT NULL
+			ORDER BY deleted_at DESC
+	`
+
+	rows, err := m.DB.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+
+		err := rows.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt, &item.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Restore takes the item out of the trash. It fails with a
+// DuplicateNameError if another item has taken its name meanwhile.
+func (m ItemModel) Restore(ctx context.Context, itemId, storeId int) (item Item, err error) {
+	stmt := `
+			UPDATE items
+			SET deleted_at = NULL, version = uuid_generate_v4(), modified_at = now()
+			WHERE id = $1 AND store_id = $2 AND deleted_at IS NOT NULL
+			RETURNING id, name, current_capacity, store_id, version, created_at, modified_at
+	`
+
+	err = m.DB.QueryRow(ctx, stmt, itemId, storeId).Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
+
+	if err == nil {
+		return item, nil
+	}
+
+	err = dbError(err)
+
+	if !errors.Is(err, ErrDuplicate) {
+		return Item{}, err
+	}
+
+	var name string
+
+	if m.DB.QueryRow(ctx, `SELECT name FROM items WHERE id = $1`, itemId).Scan(&name) != nil {
+		return Item{}, err
+	}
+
+	return Item{}, nameClash(ctx, m.DB, err, "items", "store_id", storeId, []int{itemId}, name)
+}
+
+// Purge deletes for good the items that have been in the trash since
+// before the given time, and with them their history.
+func (m ItemModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.DB.Exec(ctx, `DELETE FROM items WHERE deleted_at < $1`, before)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+type StaleItem struct {
+	Item
+	LastEatenAt *time.Time `json:"last_eaten_at"`
+	IdleDays    float64    `json:"idle_days"`
+	Score       float64    `json:"score"`
+}
+
+// Stale ranks the items of a store that still have stock by how long they
+// have been sitting untouched, i.e. since the latest of their last eaten
+// entry and their last modification, weighted by their current capacity.
+func (m ItemModel) Stale(ctx context.Context, storeId int) (items []StaleItem, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, current_capacity, store_id, version, created_at, modified_at, last_eaten, idle_days, idle_days * current_capacity AS score
+			FROM (
+				SELECT i.*, e.last_eaten,
+					COALESCE(EXTRACT(EPOCH FROM now() - GREATEST(e.last_eaten, i.modified_at, i.created_at)) / 86400, 0)::float8 AS idle_days
+				FROM items i
+					LEFT JOIN (
+						SELECT item_id, MAX(eaten_date) AS last_eaten
+						FROM eatenitems
+						GROUP BY item_id
+					) e ON e.item_id = i.id
+				WHERE i.store_id = $1 AND i.deleted_at IS NULL AND i.current_capacity > 0
+			) s
+			ORDER BY score DESC, name
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var item StaleItem
+
+		err := rows.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt, &item.LastEatenAt, &item.IdleDays, &item.Score)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, tx.Commit(ctx)
+}
+
+type LowItem struct {
+	Item
+	StoreName string `json:"store_name"`
+	UserId    string `json:"-"`
+}
+
+// ListLow returns the items at or below the warning threshold in every
+// store, grouped by store.
+func (m ItemModel) ListLow(ctx context.Context) (items []LowItem, err error) {
+	stmt := `
+			SELECT i.id, i.name, i.current_capacity, i.store_id, i.version, i.created_at, i.modified_at, s.name, s.user_id
+			FROM items i
+				JOIN stores s ON s.id = i.store_id
+			WHERE i.current_capacity <= $1 AND i.deleted_at IS NULL AND s.deleted_at IS NULL
+			ORDER BY s.id, i.name
+	`
+
+	rows, err := m.DB.Query(ctx, stmt, WarningThreshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var item LowItem
+
+		err := rows.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt, &item.StoreName, &item.UserId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/Piccio-Code/MealStore/internal/filters"
+	"github.com/Piccio-Code/MealStore/internal/testdb"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// newTestStore returns the models on a fresh database and a store of
+// "user" holding one item per name, each with capacity 3.
+func newTestStore(t *testing.T, items ...string) (Models, int, []Item) {
+	t.Helper()
+
+	models := NewModels(testdb.New(t))
+	ctx := context.Background()
+
+	name := "Casa"
+	store := Store{Name: &name}
+
+	if err := models.Stores.Insert(ctx, &store, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	var inserted []Item
+
+	for _, name := range items {
+		capacity := 3
+		item := Item{Name: &name, CurrentCapacity: &capacity, StoreId: *store.ID}
+
+		if err := models.Items.Insert(ctx, &item); err != nil {
+			t.Fatal(err)
+		}
+
+		inserted = append(inserted, item)
+	}
+
+	return models, *store.ID, inserted
+}
+
+func TestUpdateListKeepsOmittedCapacity(t *testing.T) {
+	for _, partial := range []bool{false, true} {
+		name := "atomic"
+
+		if partial {
+			name = "partial"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			models, storeId, items := newTestStore(t, "Latte")
+			ctx := context.Background()
+
+			rename := "Latte intero"
+			update := &UpdateItem{Id: items[0].Id, Name: &rename, Version: items[0].Version}
+
+			results, err := models.Items.UpdateList(ctx, storeId, []*UpdateItem{update}, partial)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if results[0].Err != nil {
+				t.Fatal(results[0].Err)
+			}
+
+			stored, err := models.Items.Get(ctx, *items[0].Id, storeId)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *stored.Name != rename || *stored.CurrentCapacity != 3 {
+				t.Errorf("stored %q with capacity %d, want %q with 3", *stored.Name, *stored.CurrentCapacity, rename)
+			}
+
+			if *stored.Version == *items[0].Version {
+				t.Error("the version did not change")
+			}
+		})
+	}
+}
+
+func TestListPageByDaysRemainingMatchesForecast(t *testing.T) {
+	models, storeId, items := newTestStore(t, "Latte", "Pane", "Riso")
+	ctx := context.Background()
+
+	eaten := []struct {
+		item, daysAgo, quantity int
+	}{
+		{0, 2, 1},
+		{0, 0, 2},
+		{1, 5, 1},
+	}
+
+	for _, e := range eaten {
+		_, err := models.Items.DB.Exec(ctx, `INSERT INTO eatenitems(quantity, item_id, eaten_date) VALUES ($1, $2, current_date - $3::int)`, e.quantity, *items[e.item].Id, e.daysAgo)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	forecastFilters, err := NewForecastFilters(url.Values{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecasts, err := models.EatenItems.Forecast(ctx, storeId, forecastFilters)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SortByDaysRemaining(forecasts)
+
+	// One item per page, so that every cursor is followed.
+	query := url.Values{"sort": {"days_remaining"}, "page_size": {"1"}}
+	var listed []ListedItem
+
+	for {
+		pageFilters, err := filters.New(query, "days_remaining")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, metadata, err := models.Items.ListPage(ctx, storeId, false, filters.Range{}, forecastFilters, pageFilters)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		listed = append(listed, page...)
+
+		if metadata.NextCursor == "" {
+			break
+		}
+
+		query.Set("cursor", metadata.NextCursor)
+	}
+
+	if len(listed) != len(forecasts) {
+		t.Fatalf("listed %d items, want %d", len(listed), len(forecasts))
+	}
+
+	days := func(d *float64) string {
+		if d == nil {
+			return "no"
+		}
+
+		return strconv.FormatFloat(*d, 'f', -1, 64)
+	}
+
+	for i, forecast := range forecasts {
+		got, want := days(listed[i].DaysRemaining), days(forecast.DaysRemaining)
+
+		if *listed[i].Id != forecast.ItemId || got != want {
+			t.Errorf("item %d: got %s with %s days, want %s with %s", i, *listed[i].Name, got, forecast.ItemName, want)
+		}
+	}
+}
+
+func TestTransferWholeItemInUse(t *testing.T) {
+	models, storeId, items := newTestStore(t, "Latte", "Pane")
+	ctx := context.Background()
+
+	name := "Ufficio"
+	other := Store{Name: &name}
+
+	if err := models.Stores.Insert(ctx, &other, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	recipeName, servings, quantity := "Cappuccino", 1, 1
+	recipe := Recipe{
+		Name:        &recipeName,
+		Servings:    &servings,
+		StoreId:     storeId,
+		Ingredients: []*Ingredient{{ItemName: items[0].Name, Quantity: &quantity}},
+	}
+
+	if err := models.Recipes.Insert(ctx, &recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := models.Items.Transfer(ctx, storeId, *items[0].Id, *items[0].Version, TransferItem{TargetStoreId: other.ID})
+
+	var inUse *ItemInUseError
+
+	if !errors.As(err, &inUse) {
+		t.Fatalf("got %v, want an ItemInUseError", err)
+	}
+
+	want := ItemReference{Kind: "recipe", Id: *recipe.Id, Name: recipeName}
+
+	if len(inUse.References) != 1 || inUse.References[0] != want {
+		t.Errorf("got references %+v, want %+v", inUse.References, want)
+	}
+
+	if _, err := models.Items.Get(ctx, *items[0].Id, storeId); err != nil {
+		t.Errorf("the item left its store: %v", err)
+	}
+
+	// An item nothing refers to moves as it is.
+	result, err := models.Items.Transfer(ctx, storeId, *items[1].Id, *items[1].Version, TransferItem{TargetStoreId: other.ID})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Target.StoreId != *other.ID || *result.Target.Id != *items[1].Id {
+		t.Errorf("got item %d in store %d, want item %d in store %d", *result.Target.Id, result.Target.StoreId, *items[1].Id, *other.ID)
+	}
+}
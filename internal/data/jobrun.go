@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+type JobRunModel struct {
+	DB *pgxpool.Pool
+}
+
+type JobRun struct {
+	Id           int        `json:"id"`
+	JobName      string     `json:"job_name"`
+	Trigger      string     `json:"trigger"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Status       string     `json:"status"`
+	Error        *string    `json:"error,omitempty"`
+}
+
+// TryLock takes the session advisory lock of the job on a dedicated
+// connection. ok is false when another instance holds it; otherwise release
+// must be called once the job is over.
+func (m JobRunModel) TryLock(ctx context.Context, jobName string) (release func(), ok bool, err error) {
+	conn, err := m.DB.Acquire(ctx)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext('job:' || $1))`, jobName).Scan(&ok)
+
+	if err != nil || !ok {
+		conn.Release()
+		return nil, false, err
+	}
+
+	release = func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext('job:' || $1))`, jobName)
+		conn.Release()
+	}
+
+	return release, true, nil
+}
+
+// Start records a new run. For scheduled runs ok is false when the same
+// tick was already claimed by another instance.
+func (m JobRunModel) Start(ctx context.Context, jobName, trigger string, scheduledFor *time.Time) (run JobRun, ok bool, err error) {
+	stmt := `
+			INSERT INTO job_runs(job_name, trigger, scheduled_for)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (job_name, scheduled_for) DO NOTHING
+			RETURNING id, job_name, trigger, scheduled_for, started_at, finished_at, status, error
+	`
+
+	err = m.DB.QueryRow(ctx, stmt, jobName, trigger, scheduledFor).Scan(&run.Id, &run.JobName, &run.Trigger, &run.ScheduledFor, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Error)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return JobRun{}, false, nil
+	}
+
+	if err != nil {
+		return JobRun{}, false, err
+	}
+
+	return run, true, nil
+}
+
+func (m JobRunModel) Finish(ctx context.Context, run *JobRun, jobErr error) error {
+	run.Status = "succeeded"
+	run.Error = nil
+
+	if jobErr != nil {
+		message := jobErr.Error()
+
+		run.Status = "failed"
+		run.Error = &message
+	}
+
+	stmt := `
+			UPDATE job_runs
+			SET finished_at = now(), status = $1, error = $2
+			WHERE id = $3
+			RETURNING finished_at
+	`
+
+	return m.DB.QueryRow(ctx, stmt, run.Status, run.Error, run.Id).Scan(&run.FinishedAt)
+}
+
+func (m JobRunModel) List(ctx context.Context, jobName string, limit int) (runs []JobRun, err error) {
+	stmt := `
+			SELECT id, job_name, trigger, scheduled_for, started_at, finished_at, status, error
+			FROM job_runs
+			WHERE job_name = $1
+			ORDER BY started_at DESC
+			LIMIT $2
+	`
+
+	rows, err := m.DB.Query(ctx, stmt, jobName, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var run JobRun
+
+		err := rows.Scan(&run.Id, &run.JobName, &run.Trigger, &run.ScheduledFor, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Error)
+
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// Cleanup deletes the finished runs started before the given time.
+func (m JobRunModel) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	stmt := `
+			DELETE FROM job_runs
+			WHERE started_at < $1 AND status <> 'running'
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, before)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
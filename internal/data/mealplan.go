@@ -0,0 +1,464 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"net/url"
+	"time"
+)
+
+const planDateLayout = "2006-01-02"
+
+type MealPlanModel struct {
+	DB *pgxpool.Pool
+}
+
+type PlannedItem struct {
+	ItemId   *int    `json:"item_id,omitempty" validate:"required"`
+	Quantity *int    `json:"quantity,omitempty" validate:"required,gte=1"`
+	ItemName *string `json:"item_name,omitempty"`
+	StoreId  *int    `json:"store_id,omitempty"`
+}
+
+type MealPlanSlot struct {
+	Id         *int           `json:"id,omitempty"`
+	PlanDate   *string        `json:"plan_date,omitempty" validate:"required,datetime=2006-01-02"`
+	MealType   *string        `json:"meal_type,omitempty" validate:"required,oneof=breakfast lunch dinner snack"`
+	DishName   *string        `json:"dish_name,omitempty" validate:"required"`
+	UserId     string         `json:"-"`
+	Items      []*PlannedItem `json:"items" validate:"dive"`
+	Version    *string        `json:"version"`
+	CreatedAt  *time.Time     `json:"created_at"`
+	ModifiedAt *time.Time     `json:"modified_at"`
+}
+
+type UpdateMealPlanSlot struct {
+	Id       *int           `json:"id,omitempty" validate:"required"`
+	PlanDate *string        `json:"plan_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	MealType *string        `json:"meal_type,omitempty" validate:"omitempty,oneof=breakfast lunch dinner snack"`
+	DishName *string        `json:"dish_name,omitempty"`
+	Items    []*PlannedItem `json:"items,omitempty" validate:"omitempty,dive"`
+	Version  *string        `json:"version" validate:"required"`
+}
+
+type MealPlanFilters struct {
+	From string `validate:"datetime=2006-01-02"`
+	To   string `validate:"datetime=2006-01-02"`
+}
+
+// NewMealPlanFilters reads the from/to query parameters, defaulting to the
+// week starting today.
+func NewMealPlanFilters(query url.Values) (filters MealPlanFilters, err error) {
+	filters.From = query.Get("from")
+	filters.To = query.Get("to")
+
+	if filters.From == "" {
+		filters.From = time.Now().Format(planDateLayout)
+	}
+
+	if filters.To == "" {
+		from, err := time.Parse(planDateLayout, filters.From)
+
+		if err != nil {
+			return MealPlanFilters{}, err
+		}
+
+		filters.To = from.AddDate(0, 0, 6).Format(planDateLayout)
+	}
+
+	v := validator.New()
+	err = v.Struct(filters)
+
+	if err != nil {
+		return MealPlanFilters{}, err
+	}
+
+	return filters, nil
+}
+
+type ProjectedDay struct {
+	Date    string `json:"date"`
+	Planned int    `json:"planned"`
+	Stock   int    `json:"stock"`
+}
+
+type ItemProjection struct {
+	ItemId          int            `json:"item_id"`
+	ItemName        string         `json:"item_name"`
+	CurrentCapacity int            `json:"current_capacity"`
+	Days            []ProjectedDay `json:"days"`
+	RunsOutOn       *string        `json:"runs_out_on"`
+}
+
+func (m MealPlanModel) insertItems(ctx context.Context, tx pgx.Tx, slot *MealPlanSlot) error {
+	lookup := `
+			SELECT i.name, i.store_id
+			FROM items i
+				JOIN stores s ON s.id = i.store_id
+			WHERE i.id = $1 AND s.user_id = $2 AND i.deleted_at IS NULL AND s.deleted_at IS NULL
+	`
+
+	stmt := `
+			INSERT INTO meal_plan_items(slot_id, item_id, quantity)
+			VALUES ($1, $2, $3)
+	`
+
+	for _, item := range slot.Items {
+		err := tx.QueryRow(ctx, lookup, *item.ItemId, slot.UserId).Scan(&item.ItemName, &item.StoreId)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrUnknownItem, *item.ItemId)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, stmt, *slot.Id, *item.ItemId, *item.Quantity)
+
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m MealPlanModel) listItems(ctx context.Context, tx pgx.Tx, slotIds []int) (map[int][]*PlannedItem, error) {
+	stmt := `
+			SELECT p.slot_id, p.item_id, p.quantity, i.name, i.store_id
+			FROM meal_plan_items p
+				JOIN items i ON i.id = p.item_id AND i.deleted_at IS NULL
+				JOIN stores s ON s.id = i.store_id AND s.deleted_at IS NULL
+			WHERE p.slot_id = ANY($1)
+			ORDER BY p.id
+	`
+
+	rows, err := tx.Query(ctx, stmt, slotIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := make(map[int][]*PlannedItem)
+
+	for rows.Next() {
+		var slot int
+		var item PlannedItem
+
+		err := rows.Scan(&slot, &item.ItemId, &item.Quantity, &item.ItemName, &item.StoreId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items[slot] = append(items[slot], &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (m MealPlanModel) List(ctx context.Context, userId string, filters MealPlanFilters) (slots []MealPlanSlot, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, to_char(plan_date, 'YYYY-MM-DD'), meal_type, dish_name, user_id, version, created_at, modified_at
+			FROM meal_plan_slots
+			WHERE user_id = $1 AND plan_date BETWEEN $2::date AND $3::date
+			ORDER BY plan_date, array_position(ARRAY['breakfast', 'lunch', 'dinner', 'snack']::varchar[], meal_type)
+	`
+
+	rows, err := tx.Query(ctx, stmt, userId, filters.From, filters.To)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int
+
+	for rows.Next() {
+		var slot MealPlanSlot
+
+		err := rows.Scan(&slot.Id, &slot.PlanDate, &slot.MealType, &slot.DishName, &slot.UserId, &slot.Version, &slot.CreatedAt, &slot.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		slots = append(slots, slot)
+		ids = append(ids, *slot.Id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := m.listItems(ctx, tx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range slots {
+		slots[i].Items = items[*slots[i].Id]
+	}
+
+	return slots, tx.Commit(ctx)
+}
+
+func (m MealPlanModel) Get(ctx context.Context, slotId int, userId string) (slot MealPlanSlot, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return MealPlanSlot{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, to_char(plan_date, 'YYYY-MM-DD'), meal_type, dish_name, user_id, version, created_at, modified_at
+			FROM meal_plan_slots
+			WHERE id = $1 AND user_id = $2
+	`
+
+	err = tx.QueryRow(ctx, stmt, slotId, userId).Scan(&slot.Id, &slot.PlanDate, &slot.MealType, &slot.DishName, &slot.UserId, &slot.Version, &slot.CreatedAt, &slot.ModifiedAt)
+
+	if err != nil {
+		return MealPlanSlot{}, dbError(err)
+	}
+
+	items, err := m.listItems(ctx, tx, []int{slotId})
+
+	if err != nil {
+		return MealPlanSlot{}, err
+	}
+
+	slot.Items = items[slotId]
+
+	return slot, tx.Commit(ctx)
+}
+
+func (m MealPlanModel) Insert(ctx context.Context, slot *MealPlanSlot) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			INSERT INTO meal_plan_slots(plan_date, meal_type, dish_name, user_id)
+			VALUES ($1::date, $2, $3, $4)
+			RETURNING id, version, created_at
+	`
+
+	args := []interface{}{*slot.PlanDate, *slot.MealType, *slot.DishName, slot.UserId}
+
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&slot.Id, &slot.Version, &slot.CreatedAt)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	err = m.insertItems(ctx, tx, slot)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Update changes the slot and, when the item list is not nil, replaces all
+// of its planned items.
+func (m MealPlanModel) Update(ctx context.Context, slot *MealPlanSlot) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			UPDATE meal_plan_slots
+			SET plan_date = $1::date, meal_type = $2, dish_name = $3, modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $4 AND version = $5 AND user_id = $6
+			RETURNING version, modified_at, created_at
+	`
+
+	args := []interface{}{*slot.PlanDate, *slot.MealType, *slot.DishName, *slot.Id, *slot.Version, slot.UserId}
+
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&slot.Version, &slot.ModifiedAt, &slot.CreatedAt)
+
+	if err != nil {
+		return updateError(err)
+	}
+
+	if slot.Items != nil {
+		_, err = tx.Exec(ctx, `DELETE FROM meal_plan_items WHERE slot_id = $1`, *slot.Id)
+
+		if err != nil {
+			return dbError(err)
+		}
+
+		err = m.insertItems(ctx, tx, slot)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		items, err := m.listItems(ctx, tx, []int{*slot.Id})
+
+		if err != nil {
+			return err
+		}
+
+		slot.Items = items[*slot.Id]
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m MealPlanModel) Delete(ctx context.Context, slotId int, userId string) error {
+	stmt := `
+			DELETE FROM meal_plan_slots
+			WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, slotId, userId)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// Projection walks the plan day by day from today, subtracting the planned
+// consumption of every item of the store from its current capacity.
+func (m MealPlanModel) Projection(ctx context.Context, storeId int, userId string, days int) (projections []ItemProjection, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	today := time.Now()
+	last := today.AddDate(0, 0, days-1)
+
+	stmt := `
+			SELECT id, name, current_capacity
+			FROM items
+			WHERE store_id = $1 AND deleted_at IS NULL
+			ORDER BY name
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var p ItemProjection
+
+		err := rows.Scan(&p.ItemId, &p.ItemName, &p.CurrentCapacity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		projections = append(projections, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+			SELECT p.item_id, to_char(s.plan_date, 'YYYY-MM-DD'), SUM(p.quantity)
+			FROM meal_plan_items p
+				JOIN meal_plan_slots s ON s.id = p.slot_id
+				JOIN items i ON i.id = p.item_id AND i.deleted_at IS NULL
+			WHERE i.store_id = $1 AND s.user_id = $2 AND s.plan_date BETWEEN $3::date AND $4::date
+			GROUP BY p.item_id, s.plan_date
+	`
+
+	rows, err = tx.Query(ctx, stmt, storeId, userId, today.Format(planDateLayout), last.Format(planDateLayout))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	planned := make(map[int]map[string]int)
+
+	for rows.Next() {
+		var itemId, quantity int
+		var date string
+
+		err := rows.Scan(&itemId, &date, &quantity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if planned[itemId] == nil {
+			planned[itemId] = make(map[string]int)
+		}
+
+		planned[itemId][date] = quantity
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range projections {
+		p := &projections[i]
+		stock := p.CurrentCapacity
+
+		for d := 0; d < days; d++ {
+			date := today.AddDate(0, 0, d).Format(planDateLayout)
+			used := planned[p.ItemId][date]
+			stock -= used
+
+			p.Days = append(p.Days, ProjectedDay{Date: date, Planned: used, Stock: stock})
+
+			if p.RunsOutOn == nil && used > 0 && stock <= 0 {
+				p.RunsOutOn = &date
+			}
+		}
+	}
+
+	return projections, tx.Commit(ctx)
+}
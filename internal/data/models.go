@@ -5,15 +5,33 @@ import (
 )
 
 type Models struct {
-	Stores     StoreModel
-	Items      ItemModel
-	EatenItems EatenItemsModel
+	Stores        StoreModel
+	Items         ItemModel
+	EatenItems    EatenItemsModel
+	Recipes       RecipeModel
+	Presets       PresetModel
+	MealPlans     MealPlanModel
+	Shopping      ShoppingListModel
+	JobRuns       JobRunModel
+	Notifications NotificationModel
+	Webhooks      WebhookModel
+	Idempotency   IdempotencyModel
+	Search        SearchModel
 }
 
 func NewModels(db *pgxpool.Pool) Models {
 	return Models{
-		Stores:     StoreModel{DB: db},
-		Items:      ItemModel{DB: db},
-		EatenItems: EatenItemsModel{DB: db},
+		Stores:        StoreModel{DB: db},
+		Items:         ItemModel{DB: db},
+		EatenItems:    EatenItemsModel{DB: db},
+		Recipes:       RecipeModel{DB: db},
+		Presets:       PresetModel{DB: db},
+		MealPlans:     MealPlanModel{DB: db},
+		Shopping:      ShoppingListModel{DB: db},
+		JobRuns:       JobRunModel{DB: db},
+		Notifications: NotificationModel{DB: db},
+		Webhooks:      WebhookModel{DB: db},
+		Idempotency:   IdempotencyModel{DB: db},
+		Search:        SearchModel{DB: db},
 	}
 }
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type NotificationModel struct {
+	DB *pgxpool.Pool
+}
+
+type NotificationPreferences struct {
+	UserId           string     `json:"-"`
+	ChatId           *string    `json:"chat_id,omitempty"`
+	LowStock         *bool      `json:"low_stock,omitempty"`
+	Digest           *bool      `json:"digest,omitempty"`
+	RepeatAfterHours *int       `json:"repeat_after_hours,omitempty" validate:"omitempty,gte=0"`
+	ModifiedAt       *time.Time `json:"modified_at"`
+}
+
+// GetPreferences returns the stored preferences of the user or, when there
+// are none, the defaults: low-stock alerts and the daily digest on, sent to
+// the user's own chat, alerts once per item until it is restocked.
+func (m NotificationModel) GetPreferences(ctx context.Context, userId string) (prefs NotificationPreferences, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT user_id, chat_id, low_stock, digest, repeat_after_hours, modified_at
+			 FROM notification_preferences
+			 WHERE user_id = $1`
+
+	err = m.DB.QueryRow(ctx, stmt, userId).Scan(&prefs.UserId, &prefs.ChatId, &prefs.LowStock, &prefs.Digest, &prefs.RepeatAfterHours, &prefs.ModifiedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		lowStock, digest, repeat := true, true, 0
+
+		return NotificationPreferences{UserId: userId, ChatId: &userId, LowStock: &lowStock, Digest: &digest, RepeatAfterHours: &repeat}, nil
+	}
+
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+
+	return prefs, nil
+}
+
+func (m NotificationModel) UpsertPreferences(ctx context.Context, prefs *NotificationPreferences) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO notification_preferences(user_id, chat_id, low_stock, digest, repeat_after_hours, modified_at)
+			 VALUES ($1, $2, $3, $4, $5, now())
+			 ON CONFLICT (user_id) DO UPDATE
+			 SET chat_id = $2, low_stock = $3, digest = $4, repeat_after_hours = $5, modified_at = now()
+			 RETURNING modified_at`
+
+	args := []interface{}{prefs.UserId, *prefs.ChatId, *prefs.LowStock, *prefs.Digest, *prefs.RepeatAfterHours}
+
+	return m.DB.QueryRow(ctx, stmt, args...).Scan(&prefs.ModifiedAt)
+}
+
+// ClaimAlert records that a low-stock alert for the item is about to be
+// sent. It returns false when one was already sent and, if repeatAfterHours
+// is positive, less than that many hours ago.
+func (m NotificationModel) ClaimAlert(ctx context.Context, itemId, repeatAfterHours int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO low_stock_alerts(item_id)
+			 VALUES ($1)
+			 ON CONFLICT (item_id) DO UPDATE
+			 SET alerted_at = now()
+			 WHERE $2 > 0 AND low_stock_alerts.alerted_at < now() - make_interval(hours => $2)
+			 RETURNING item_id`
+
+	err := m.DB.QueryRow(ctx, stmt, itemId, repeatAfterHours).Scan(&itemId)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ClearAlert re-arms the alert of the item, after it was restocked or after
+// the alert could not be delivered.
+func (m NotificationModel) ClearAlert(ctx context.Context, itemId int) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, `DELETE FROM low_stock_alerts WHERE item_id = $1`, itemId)
+
+	return err
+}
@@ -0,0 +1,320 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+var ErrUnknownItem = errors.New("unknown item")
+
+type PresetModel struct {
+	DB *pgxpool.Pool
+}
+
+type PresetEntry struct {
+	ItemId   *int    `json:"item_id,omitempty" validate:"required"`
+	Quantity *int    `json:"quantity,omitempty" validate:"required,gte=1"`
+	ItemName *string `json:"item_name,omitempty"`
+	Missing  bool    `json:"missing"`
+}
+
+type Preset struct {
+	Id         *int           `json:"id,omitempty"`
+	Name       *string        `json:"name,omitempty" validate:"required"`
+	StoreId    int            `json:"-"`
+	Entries    []*PresetEntry `json:"entries" validate:"required,min=1,dive"`
+	Version    *string        `json:"version"`
+	CreatedAt  *time.Time     `json:"created_at"`
+	ModifiedAt *time.Time     `json:"modified_at"`
+}
+
+type UpdatePreset struct {
+	Id      *int           `json:"id,omitempty" validate:"required"`
+	Name    *string        `json:"name,omitempty"`
+	Entries []*PresetEntry `json:"entries,omitempty" validate:"omitempty,min=1,dive"`
+	Version *string        `json:"version" validate:"required"`
+}
+
+// EatenItems expands the preset into eaten entries, scaling every quantity
+// by multiplier. Entries whose item no longer exists are skipped and their
+// ids returned separately.
+func (p Preset) EatenItems(multiplier int) (eatenItems []*EatenItem, missing []int) {
+	missing = []int{}
+
+	for _, entry := range p.Entries {
+		if entry.Missing {
+			missing = append(missing, *entry.ItemId)
+			continue
+		}
+
+		eatenItems = append(eatenItems, &EatenItem{
+			Quantity: *entry.Quantity * multiplier,
+			ItemId:   *entry.ItemId,
+		})
+	}
+
+	return eatenItems, missing
+}
+
+func (m PresetModel) insertEntries(ctx context.Context, tx pgx.Tx, preset *Preset) error {
+	lookup := `
+			SELECT name
+			FROM items
+			WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL
+	`
+
+	stmt := `
+			INSERT INTO meal_preset_entries(preset_id, item_id, quantity)
+			VALUES ($1, $2, $3)
+	`
+
+	for _, entry := range preset.Entries {
+		err := tx.QueryRow(ctx, lookup, *entry.ItemId, preset.StoreId).Scan(&entry.ItemName)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrUnknownItem, *entry.ItemId)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, stmt, *preset.Id, *entry.ItemId, *entry.Quantity)
+
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m PresetModel) listEntries(ctx context.Context, tx pgx.Tx, storeId int, presetId *int) (map[int][]*PresetEntry, error) {
+	stmt := `
+			SELECT e.preset_id, e.item_id, e.quantity, i.name
+			FROM meal_preset_entries e
+				JOIN meal_presets p ON p.id = e.preset_id
+				LEFT JOIN items i ON i.id = e.item_id AND i.store_id = p.store_id AND i.deleted_at IS NULL
+			WHERE p.store_id = $1 AND (p.id = $2 OR $2 IS NULL)
+			ORDER BY e.id
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId, presetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make(map[int][]*PresetEntry)
+
+	for rows.Next() {
+		var preset int
+		var entry PresetEntry
+
+		err := rows.Scan(&preset, &entry.ItemId, &entry.Quantity, &entry.ItemName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Missing = entry.ItemName == nil
+		entries[preset] = append(entries[preset], &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (m PresetModel) List(ctx context.Context, storeId int) (presets []Preset, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, store_id, version, created_at, modified_at
+			FROM meal_presets
+			WHERE store_id = $1
+			ORDER BY name
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var preset Preset
+
+		err := rows.Scan(&preset.Id, &preset.Name, &preset.StoreId, &preset.Version, &preset.CreatedAt, &preset.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		presets = append(presets, preset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.listEntries(ctx, tx, storeId, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range presets {
+		presets[i].Entries = entries[*presets[i].Id]
+	}
+
+	return presets, tx.Commit(ctx)
+}
+
+func (m PresetModel) Get(ctx context.Context, presetId, storeId int) (preset Preset, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return Preset{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, store_id, version, created_at, modified_at
+			FROM meal_presets
+			WHERE id = $1 AND store_id = $2
+	`
+
+	err = tx.QueryRow(ctx, stmt, presetId, storeId).Scan(&preset.Id, &preset.Name, &preset.StoreId, &preset.Version, &preset.CreatedAt, &preset.ModifiedAt)
+
+	if err != nil {
+		return Preset{}, dbError(err)
+	}
+
+	entries, err := m.listEntries(ctx, tx, storeId, &presetId)
+
+	if err != nil {
+		return Preset{}, err
+	}
+
+	preset.Entries = entries[presetId]
+
+	return preset, tx.Commit(ctx)
+}
+
+func (m PresetModel) Insert(ctx context.Context, preset *Preset) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			INSERT INTO meal_presets(name, store_id)
+			VALUES ($1, $2)
+			RETURNING id, version, created_at
+	`
+
+	err = tx.QueryRow(ctx, stmt, *preset.Name, preset.StoreId).Scan(&preset.Id, &preset.Version, &preset.CreatedAt)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	err = m.insertEntries(ctx, tx, preset)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Update renames the preset and, when the entry list is not nil, replaces
+// all of its entries.
+func (m PresetModel) Update(ctx context.Context, preset *Preset) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			UPDATE meal_presets
+			SET name = $1, modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $2 AND version = $3 AND store_id = $4
+			RETURNING version, modified_at, created_at
+	`
+
+	args := []interface{}{*preset.Name, *preset.Id, *preset.Version, preset.StoreId}
+
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&preset.Version, &preset.ModifiedAt, &preset.CreatedAt)
+
+	if err != nil {
+		return updateError(err)
+	}
+
+	if preset.Entries != nil {
+		_, err = tx.Exec(ctx, `DELETE FROM meal_preset_entries WHERE preset_id = $1`, *preset.Id)
+
+		if err != nil {
+			return dbError(err)
+		}
+
+		err = m.insertEntries(ctx, tx, preset)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		entries, err := m.listEntries(ctx, tx, preset.StoreId, preset.Id)
+
+		if err != nil {
+			return err
+		}
+
+		preset.Entries = entries[*preset.Id]
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m PresetModel) Delete(ctx context.Context, presetId, storeId int) error {
+	stmt := `
+			DELETE FROM meal_presets
+			WHERE id = $1 AND store_id = $2
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, presetId, storeId)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
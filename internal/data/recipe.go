@@ -0,0 +1,329 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+var ErrUnknownIngredient = errors.New("unknown ingredient")
+
+type RecipeModel struct {
+	DB *pgxpool.Pool
+}
+
+type Ingredient struct {
+	ItemId          *int    `json:"item_id,omitempty"`
+	ItemName        *string `json:"item_name,omitempty" validate:"required"`
+	Quantity        *int    `json:"quantity,omitempty" validate:"required,gte=1"`
+	CurrentCapacity *int    `json:"current_capacity,omitempty"`
+	Short           bool    `json:"short"`
+}
+
+type Recipe struct {
+	Id               *int          `json:"id,omitempty"`
+	Name             *string       `json:"name,omitempty" validate:"required"`
+	Servings         *int          `json:"servings,omitempty" validate:"required,gte=1"`
+	Instructions     *string       `json:"instructions,omitempty"`
+	StoreId          int           `json:"-"`
+	Ingredients      []*Ingredient `json:"ingredients" validate:"required,min=1,dive"`
+	ShortIngredients []string      `json:"short_ingredients"`
+	Version          *string       `json:"version"`
+	CreatedAt        *time.Time    `json:"created_at"`
+	ModifiedAt       *time.Time    `json:"modified_at"`
+}
+
+type UpdateRecipe struct {
+	Id           *int          `json:"id,omitempty" validate:"required"`
+	Name         *string       `json:"name,omitempty"`
+	Servings     *int          `json:"servings,omitempty" validate:"omitempty,gte=1"`
+	Instructions *string       `json:"instructions,omitempty"`
+	Ingredients  []*Ingredient `json:"ingredients,omitempty" validate:"omitempty,min=1,dive"`
+	Version      *string       `json:"version" validate:"required"`
+}
+
+// checkShortages marks every ingredient whose item has less stock than the
+// recipe asks for and collects their names on the recipe.
+func (r *Recipe) checkShortages() {
+	r.ShortIngredients = []string{}
+
+	for _, ingredient := range r.Ingredients {
+		ingredient.Short = *ingredient.CurrentCapacity < *ingredient.Quantity
+
+		if ingredient.Short {
+			r.ShortIngredients = append(r.ShortIngredients, *ingredient.ItemName)
+		}
+	}
+}
+
+func (m RecipeModel) insertIngredients(ctx context.Context, tx pgx.Tx, recipe *Recipe) error {
+	lookup := `
+			SELECT id, name, current_capacity
+			FROM items
+			WHERE name_norm = normalize_name($1) AND store_id = $2 AND deleted_at IS NULL
+	`
+
+	stmt := `
+			INSERT INTO recipe_ingredients(recipe_id, item_id, quantity)
+			VALUES ($1, $2, $3)
+	`
+
+	for _, ingredient := range recipe.Ingredients {
+		err := tx.QueryRow(ctx, lookup, *ingredient.ItemName, recipe.StoreId).Scan(&ingredient.ItemId, &ingredient.ItemName, &ingredient.CurrentCapacity)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrUnknownIngredient, *ingredient.ItemName)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, stmt, *recipe.Id, *ingredient.ItemId, *ingredient.Quantity)
+
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	recipe.checkShortages()
+
+	return nil
+}
+
+func (m RecipeModel) listIngredients(ctx context.Context, tx pgx.Tx, storeId int, recipeId *int) (map[int][]*Ingredient, error) {
+	stmt := `
+			SELECT ri.recipe_id, i.id, i.name, ri.quantity, i.current_capacity
+			FROM recipe_ingredients ri
+				JOIN recipes r ON r.id = ri.recipe_id
+				JOIN items i ON i.id = ri.item_id AND i.deleted_at IS NULL
+			WHERE r.store_id = $1 AND (r.id = $2 OR $2 IS NULL)
+			ORDER BY ri.id
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId, recipeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ingredients := make(map[int][]*Ingredient)
+
+	for rows.Next() {
+		var recipe int
+		var ingredient Ingredient
+
+		err := rows.Scan(&recipe, &ingredient.ItemId, &ingredient.ItemName, &ingredient.Quantity, &ingredient.CurrentCapacity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ingredients[recipe] = append(ingredients[recipe], &ingredient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ingredients, nil
+}
+
+func (m RecipeModel) List(ctx context.Context, storeId int) (recipes []Recipe, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, servings, instructions, store_id, version, created_at, modified_at
+			FROM recipes
+			WHERE store_id = $1
+			ORDER BY name
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipe Recipe
+
+		err := rows.Scan(&recipe.Id, &recipe.Name, &recipe.Servings, &recipe.Instructions, &recipe.StoreId, &recipe.Version, &recipe.CreatedAt, &recipe.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ingredients, err := m.listIngredients(ctx, tx, storeId, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range recipes {
+		recipes[i].Ingredients = ingredients[*recipes[i].Id]
+		recipes[i].checkShortages()
+	}
+
+	return recipes, tx.Commit(ctx)
+}
+
+func (m RecipeModel) Get(ctx context.Context, recipeId, storeId int) (recipe Recipe, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, servings, instructions, store_id, version, created_at, modified_at
+			FROM recipes
+			WHERE id = $1 AND store_id = $2
+	`
+
+	err = tx.QueryRow(ctx, stmt, recipeId, storeId).Scan(&recipe.Id, &recipe.Name, &recipe.Servings, &recipe.Instructions, &recipe.StoreId, &recipe.Version, &recipe.CreatedAt, &recipe.ModifiedAt)
+
+	if err != nil {
+		return Recipe{}, dbError(err)
+	}
+
+	ingredients, err := m.listIngredients(ctx, tx, storeId, &recipeId)
+
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	recipe.Ingredients = ingredients[recipeId]
+	recipe.checkShortages()
+
+	return recipe, tx.Commit(ctx)
+}
+
+func (m RecipeModel) Insert(ctx context.Context, recipe *Recipe) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if recipe.Instructions == nil {
+		recipe.Instructions = new(string)
+	}
+
+	stmt := `
+			INSERT INTO recipes(name, servings, instructions, store_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, version, created_at
+	`
+
+	args := []interface{}{*recipe.Name, *recipe.Servings, *recipe.Instructions, recipe.StoreId}
+
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&recipe.Id, &recipe.Version, &recipe.CreatedAt)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	err = m.insertIngredients(ctx, tx, recipe)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Update replaces the recipe fields and, when the ingredient list is not nil,
+// the whole ingredient list.
+func (m RecipeModel) Update(ctx context.Context, recipe *Recipe) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			UPDATE recipes
+			SET name = $1, servings = $2, instructions = $3, modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $4 AND version = $5 AND store_id = $6
+			RETURNING version, modified_at, created_at
+	`
+
+	args := []interface{}{*recipe.Name, *recipe.Servings, *recipe.Instructions, *recipe.Id, *recipe.Version, recipe.StoreId}
+
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&recipe.Version, &recipe.ModifiedAt, &recipe.CreatedAt)
+
+	if err != nil {
+		return updateError(err)
+	}
+
+	if recipe.Ingredients != nil {
+		_, err = tx.Exec(ctx, `DELETE FROM recipe_ingredients WHERE recipe_id = $1`, *recipe.Id)
+
+		if err != nil {
+			return dbError(err)
+		}
+
+		err = m.insertIngredients(ctx, tx, recipe)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		ingredients, err := m.listIngredients(ctx, tx, recipe.StoreId, recipe.Id)
+
+		if err != nil {
+			return err
+		}
+
+		recipe.Ingredients = ingredients[*recipe.Id]
+		recipe.checkShortages()
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m RecipeModel) Delete(ctx context.Context, recipeId, storeId int) error {
+	stmt := `
+			DELETE FROM recipes
+			WHERE id = $1 AND store_id = $2
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, recipeId, storeId)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sort"
+	"time"
+)
+
+const (
+	SearchItem  = "item"
+	SearchStore = "store"
+)
+
+type SearchModel struct {
+	DB *pgxpool.Pool
+}
+
+// SearchStoreRef is the store a search result belongs to.
+type SearchStoreRef struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SearchResult is an item or a store whose name looks like the query. Score
+// goes from 0 to 1.
+type SearchResult struct {
+	Type  string         `json:"type"`
+	Score float64        `json:"score"`
+	Item  *Item          `json:"item,omitempty"`
+	Store SearchStoreRef `json:"store"`
+}
+
+// Search looks for items and stores of the user named like query, ignoring
+// case, accents and typos, and returns the best limit matches first. A
+// name matches when it is similar to the query as a whole or contains a
+// word similar to it, so "mozarella" finds "Mozzarella di bufala".
+func (m SearchModel) Search(ctx context.Context, userId, query string, limit int) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	itemsStmt := `
+			SELECT i.id, i.name, i.current_capacity, i.store_id, i.version, i.created_at, i.modified_at, s.name,
+				GREATEST(similarity(search_name(i.name), search_name($2)), word_similarity(search_name($2), search_name(i.name))) AS score
+			FROM items i
+				JOIN stores s ON s.id = i.store_id
+			WHERE s.user_id = $1 AND i.deleted_at IS NULL AND s.deleted_at IS NULL AND (search_name(i.name) % search_name($2) OR search_name($2) <% search_name(i.name))
+			ORDER BY score DESC, i.name
+			LIMIT $3
+	`
+
+	rows, err := m.DB.Query(ctx, itemsStmt, userId, query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var results []SearchResult
+
+	for rows.Next() {
+		var item Item
+		result := SearchResult{Type: SearchItem, Item: &item}
+
+		err := rows.Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt, &result.Store.Name, &result.Score)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Store.Id = item.StoreId
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	storesStmt := `
+			SELECT s.id, s.name,
+				GREATEST(similarity(search_name(s.name), search_name($2)), word_similarity(search_name($2), search_name(s.name))) AS score
+			FROM stores s
+			WHERE s.user_id = $1 AND s.deleted_at IS NULL AND (search_name(s.name) % search_name($2) OR search_name($2) <% search_name(s.name))
+			ORDER BY score DESC, s.name
+			LIMIT $3
+	`
+
+	rows, err = m.DB.Query(ctx, storesStmt, userId, query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		result := SearchResult{Type: SearchStore}
+
+		err := rows.Scan(&result.Store.Id, &result.Store.Name, &result.Score)
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
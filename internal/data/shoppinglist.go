@@ -0,0 +1,283 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// DefaultTargetCapacity is the stock a generated shopping list brings an
+// item back to when the request does not ask for another target.
+const DefaultTargetCapacity = 5
+
+var ErrEntryChecked = errors.New("the entry does not exist or is already checked")
+
+type ShoppingListModel struct {
+	DB *pgxpool.Pool
+}
+
+type ShoppingListEntry struct {
+	Id        *int       `json:"id,omitempty"`
+	ItemId    *int       `json:"item_id,omitempty"`
+	ItemName  *string    `json:"item_name,omitempty"`
+	Quantity  *int       `json:"quantity,omitempty"`
+	Checked   bool       `json:"checked"`
+	CheckedAt *time.Time `json:"checked_at"`
+}
+
+type ShoppingList struct {
+	Id         *int                 `json:"id,omitempty"`
+	Name       *string              `json:"name,omitempty"`
+	StoreId    int                  `json:"-"`
+	Entries    []*ShoppingListEntry `json:"entries"`
+	Version    *string              `json:"version"`
+	CreatedAt  *time.Time           `json:"created_at"`
+	ModifiedAt *time.Time           `json:"modified_at"`
+}
+
+func (m ShoppingListModel) listEntries(ctx context.Context, tx pgx.Tx, storeId int, listId *int) (map[int][]*ShoppingListEntry, error) {
+	stmt := `
+			SELECT e.list_id, e.id, e.item_id, i.name, e.quantity, e.checked, e.checked_at
+			FROM shopping_list_entries e
+				JOIN shopping_lists l ON l.id = e.list_id
+				JOIN items i ON i.id = e.item_id AND i.deleted_at IS NULL
+			WHERE l.store_id = $1 AND (l.id = $2 OR $2 IS NULL)
+			ORDER BY e.checked, i.name
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId, listId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make(map[int][]*ShoppingListEntry)
+
+	for rows.Next() {
+		var list int
+		var entry ShoppingListEntry
+
+		err := rows.Scan(&list, &entry.Id, &entry.ItemId, &entry.ItemName, &entry.Quantity, &entry.Checked, &entry.CheckedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries[list] = append(entries[list], &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (m ShoppingListModel) List(ctx context.Context, storeId int) (lists []ShoppingList, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, store_id, version, created_at, modified_at
+			FROM shopping_lists
+			WHERE store_id = $1
+			ORDER BY created_at DESC
+	`
+
+	rows, err := tx.Query(ctx, stmt, storeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var list ShoppingList
+
+		err := rows.Scan(&list.Id, &list.Name, &list.StoreId, &list.Version, &list.CreatedAt, &list.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := m.listEntries(ctx, tx, storeId, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range lists {
+		lists[i].Entries = entries[*lists[i].Id]
+	}
+
+	return lists, tx.Commit(ctx)
+}
+
+func (m ShoppingListModel) Get(ctx context.Context, listId, storeId int) (list ShoppingList, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return ShoppingList{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, name, store_id, version, created_at, modified_at
+			FROM shopping_lists
+			WHERE id = $1 AND store_id = $2
+	`
+
+	err = tx.QueryRow(ctx, stmt, listId, storeId).Scan(&list.Id, &list.Name, &list.StoreId, &list.Version, &list.CreatedAt, &list.ModifiedAt)
+
+	if err != nil {
+		return ShoppingList{}, dbError(err)
+	}
+
+	entries, err := m.listEntries(ctx, tx, storeId, &listId)
+
+	if err != nil {
+		return ShoppingList{}, err
+	}
+
+	list.Entries = entries[listId]
+
+	return list, tx.Commit(ctx)
+}
+
+// Generate stores a new list with one entry per low item, each suggesting
+// the quantity that brings the item back to target.
+func (m ShoppingListModel) Generate(ctx context.Context, list *ShoppingList, lowItems []Item, target int) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			INSERT INTO shopping_lists(name, store_id)
+			VALUES ($1, $2)
+			RETURNING id, version, created_at
+	`
+
+	err = tx.QueryRow(ctx, stmt, *list.Name, list.StoreId).Scan(&list.Id, &list.Version, &list.CreatedAt)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	stmt = `
+			INSERT INTO shopping_list_entries(list_id, item_id, quantity)
+			VALUES ($1, $2, $3)
+			RETURNING id
+	`
+
+	list.Entries = []*ShoppingListEntry{}
+
+	for _, item := range lowItems {
+		quantity := max(target-*item.CurrentCapacity, 1)
+
+		entry := ShoppingListEntry{ItemId: item.Id, ItemName: item.Name, Quantity: &quantity}
+
+		err = tx.QueryRow(ctx, stmt, *list.Id, *item.Id, quantity).Scan(&entry.Id)
+
+		if err != nil {
+			return dbError(err)
+		}
+
+		list.Entries = append(list.Entries, &entry)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Check marks the entry as bought and adds its quantity, or the one actually
+// bought when quantity is not nil, to the item's current capacity.
+func (m ShoppingListModel) Check(ctx context.Context, entryId, listId, storeId int, quantity *int) (item Item, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return Item{}, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			UPDATE shopping_list_entries e
+			SET checked = TRUE, checked_at = now(), quantity = COALESCE($4, e.quantity)
+			FROM shopping_lists l
+			WHERE e.id = $1 AND e.list_id = $2 AND l.id = e.list_id AND l.store_id = $3 AND NOT e.checked
+			RETURNING e.item_id, e.quantity
+	`
+
+	var itemId, bought int
+
+	err = tx.QueryRow(ctx, stmt, entryId, listId, storeId, quantity).Scan(&itemId, &bought)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Item{}, ErrEntryChecked
+	}
+
+	if err != nil {
+		return Item{}, err
+	}
+
+	stmt = `
+			UPDATE items
+			SET current_capacity = current_capacity + $1, modified_at = now(), version = uuid_generate_v4()
+			WHERE id = $2 AND store_id = $3 AND deleted_at IS NULL
+			RETURNING id, name, current_capacity, store_id, version, created_at, modified_at
+	`
+
+	err = tx.QueryRow(ctx, stmt, bought, itemId, storeId).Scan(&item.Id, &item.Name, &item.CurrentCapacity, &item.StoreId, &item.Version, &item.CreatedAt, &item.ModifiedAt)
+
+	if err != nil {
+		return Item{}, dbError(err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE shopping_lists SET modified_at = now(), version = uuid_generate_v4() WHERE id = $1`, listId)
+
+	if err != nil {
+		return Item{}, dbError(err)
+	}
+
+	return item, tx.Commit(ctx)
+}
+
+func (m ShoppingListModel) Delete(ctx context.Context, listId, storeId int) error {
+	stmt := `
+			DELETE FROM shopping_lists
+			WHERE id = $1 AND store_id = $2
+	`
+
+	result, err := m.DB.Exec(ctx, stmt, listId, storeId)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"errors"
+	"github.com/Piccio-Code/MealStore/internal/filters"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"time"
 )
@@ -14,6 +15,7 @@ type Store struct {
 	Version    *string    `json:"version,omitempty"`
 	CreatedAt  *time.Time `json:"created_at,omitempty"`
 	ModifiedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 type StoreModel struct {
@@ -30,7 +32,9 @@ func (m StoreModel) Insert(ctx context.Context, newStore *Store, userId string)
 
 	args := []interface{}{newStore.Name, userId}
 
-	return m.DB.QueryRow(ctx, stmt, args...).Scan(&newStore.ID, &newStore.Version, &newStore.CreatedAt)
+	err := dbError(m.DB.QueryRow(ctx, stmt, args...).Scan(&newStore.ID, &newStore.Version, &newStore.CreatedAt))
+
+	return nameClash(ctx, m.DB, err, "stores", "user_id", userId, nil, *newStore.Name)
 }
 
 func (m StoreModel) Get(ctx context.Context, storeId int, userId string) (store Store, err error) {
@@ -39,14 +43,14 @@ func (m StoreModel) Get(ctx context.Context, storeId int, userId string) (store
 
 	stmt := `SELECT id, name, user_id, created_at, version, modified_at
 			 FROM stores
-			 WHERE id = $1 AND user_id = $2`
+			 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
 	args := []interface{}{storeId, userId}
 
 	err = m.DB.QueryRow(ctx, stmt, args...).Scan(&store.ID, &store.Name, &store.UserID, &store.CreatedAt, &store.Version, &store.ModifiedAt)
 
 	if err != nil {
-		return Store{}, err
+		return Store{}, dbError(err)
 	}
 
 	return store, nil
@@ -58,7 +62,7 @@ func (m StoreModel) List(ctx context.Context, userId string) (stores []Store, er
 
 	stmt := `SELECT id, name, user_id, created_at, version, modified_at
 			 FROM stores
-			 WHERE user_id = $1`
+			 WHERE user_id = $1 AND deleted_at IS NULL`
 
 	rows, err := m.DB.Query(ctx, stmt, userId)
 
@@ -87,50 +91,168 @@ func (m StoreModel) List(ctx context.Context, userId string) (stores []Store, er
 	return stores, nil
 }
 
+// ListAll returns the stores of every user, grouped by user.
+func (m StoreModel) ListAll(ctx context.Context) (stores []Store, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, name, user_id, created_at, version, modified_at
+			 FROM stores
+			 WHERE deleted_at IS NULL
+			 ORDER BY user_id, name`
+
+	rows, err := m.DB.Query(ctx, stmt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var store Store
+
+		err := rows.Scan(&store.ID, &store.Name, &store.UserID, &store.CreatedAt, &store.Version, &store.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stores = append(stores, store)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stores, nil
+}
+
+// StoreSortColumns are the fields stores can be sorted by.
+var StoreSortColumns = map[string]filters.Column{
+	"id":          {Expr: "id", Type: "int"},
+	"name":        {Expr: "name", Type: "text"},
+	"created_at":  {Expr: "created_at", Type: "timestamp"},
+	"modified_at": {Expr: "COALESCE(modified_at, created_at)", Type: "timestamp"},
+}
+
+// ListPage is List one page at a time.
+func (m StoreModel) ListPage(ctx context.Context, userId string, f filters.Filters) (stores []Store, metadata filters.Metadata, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRow(ctx, `SELECT count(*) FROM stores WHERE user_id = $1 AND deleted_at IS NULL`, userId).Scan(&metadata.TotalRecords)
+
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	sortKey, where, orderLimit, args := f.Keyset(StoreSortColumns, 2)
+
+	stmt := `SELECT id, name, user_id, created_at, version, modified_at, ` + sortKey + `
+			 FROM stores
+			 WHERE user_id = $1 AND deleted_at IS NULL AND ` + where + `
+			 ` + orderLimit
+
+	rows, err := m.DB.Query(ctx, stmt, append([]any{userId}, args...)...)
+
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	var sortKeys []string
+
+	for rows.Next() {
+		var store Store
+		var key string
+
+		err := rows.Scan(&store.ID, &store.Name, &store.UserID, &store.CreatedAt, &store.Version, &store.ModifiedAt, &key)
+
+		if err != nil {
+			return nil, filters.Metadata{}, err
+		}
+
+		stores = append(stores, store)
+		sortKeys = append(sortKeys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	fetched := len(stores)
+	metadata.PageSize = f.PageSize
+
+	if fetched > f.PageSize {
+		stores = stores[:f.PageSize]
+		metadata.NextCursor = f.NextCursor(fetched, sortKeys[f.PageSize-1], *stores[f.PageSize-1].ID)
+	}
+
+	return stores, metadata, nil
+}
+
 func (m StoreModel) Update(ctx context.Context, newStore *Store, userId string) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	stmt := `UPDATE stores
 			 SET name = $1, version = uuid_generate_v4(), modified_at = NOW()
-			 WHERE id = $2 AND user_id = $3 AND version = $4
+			 WHERE id = $2 AND user_id = $3 AND version = $4 AND deleted_at IS NULL
 			 RETURNING version, modified_at`
 
 	args := []interface{}{newStore.Name, newStore.ID, userId, newStore.Version}
 
-	return m.DB.QueryRow(ctx, stmt, args...).Scan(&newStore.Version, &newStore.ModifiedAt)
+	err := updateError(m.DB.QueryRow(ctx, stmt, args...).Scan(&newStore.Version, &newStore.ModifiedAt))
+
+	if err != nil && newStore.ID != nil && newStore.Name != nil {
+		err = nameClash(ctx, m.DB, err, "stores", "user_id", userId, []int{*newStore.ID}, *newStore.Name)
+	}
+
+	return err
 }
 
-func (m StoreModel) Delete(ctx context.Context, storeId int, userId string) error {
+// Delete moves the store to the trash, with its items and their history,
+// until Restore or Purge. With a version it only does so if the store is
+// still at that version and returns ErrEditConflict otherwise.
+func (m StoreModel) Delete(ctx context.Context, storeId int, userId string, version *string) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	stmt := `DELETE FROM stores
-			 WHERE id = $1 AND user_id = $2`
+	stmt := `UPDATE stores
+			 SET deleted_at = NOW(), version = uuid_generate_v4()
+			 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($3::uuid IS NULL OR version = $3::uuid)`
 
-	args := []interface{}{storeId, userId}
+	args := []interface{}{storeId, userId, version}
 
 	result, err := m.DB.Exec(ctx, stmt, args...)
 
 	if err != nil {
-		return err
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 && version != nil {
+		return ErrEditConflict
 	}
 
 	if result.RowsAffected() == 0 {
-		return errors.New("0 effected rows")
+		return ErrNotFound
 	}
 
 	return err
 }
 
+// GetID finds a store by name the way names are kept unique: regardless of
+// case and extra spaces.
 func (m StoreModel) GetID(ctx context.Context, storeName string, userId string) (storeId int, err error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	stmt := `
-			SELECT id 
+			SELECT id
 			FROM stores
-			WHERE name = $1 AND user_id = $2
+			WHERE name_norm = normalize_name($1) AND user_id = $2 AND deleted_at IS NULL
 			`
 
 	args := []interface{}{storeName, userId}
@@ -138,8 +260,89 @@ func (m StoreModel) GetID(ctx context.Context, storeName string, userId string)
 	err = m.DB.QueryRow(ctx, stmt, args...).Scan(&storeId)
 
 	if err != nil {
-		return 0, err
+		return 0, dbError(err)
 	}
 
 	return storeId, nil
 }
+
+// ListDeleted returns the stores in the trash, most recently deleted first.
+func (m StoreModel) ListDeleted(ctx context.Context, userId string) (stores []Store, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, name, user_id, created_at, version, modified_at, deleted_at
+			 FROM stores
+			 WHERE user_id = $1 AND deleted_at IS NOT NULL
+			 ORDER BY deleted_at DESC`
+
+	rows, err := m.DB.Query(ctx, stmt, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var store Store
+
+		err := rows.Scan(&store.ID, &store.Name, &store.UserID, &store.CreatedAt, &store.Version, &store.ModifiedAt, &store.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stores = append(stores, store)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stores, nil
+}
+
+// Restore takes the store out of the trash. It fails with a
+// DuplicateNameError if another store has taken its name meanwhile.
+func (m StoreModel) Restore(ctx context.Context, storeId int, userId string) (store Store, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE stores
+			 SET deleted_at = NULL, version = uuid_generate_v4(), modified_at = NOW()
+			 WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+			 RETURNING id, name, user_id, created_at, version, modified_at`
+
+	err = m.DB.QueryRow(ctx, stmt, storeId, userId).Scan(&store.ID, &store.Name, &store.UserID, &store.CreatedAt, &store.Version, &store.ModifiedAt)
+
+	if err == nil {
+		return store, nil
+	}
+
+	err = dbError(err)
+
+	if !errors.Is(err, ErrDuplicate) {
+		return Store{}, err
+	}
+
+	var name string
+
+	if m.DB.QueryRow(ctx, `SELECT name FROM stores WHERE id = $1`, storeId).Scan(&name) != nil {
+		return Store{}, err
+	}
+
+	return Store{}, nameClash(ctx, m.DB, err, "stores", "user_id", userId, []int{storeId}, name)
+}
+
+// Purge deletes for good the stores that have been in the trash since
+// before the given time, and with them their items and history.
+func (m StoreModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.DB.Exec(ctx, `DELETE FROM stores WHERE deleted_at < $1`, before)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,43 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/Piccio-Code/MealStore/internal/testdb"
+	"testing"
+)
+
+func TestRestoreStoreNameClash(t *testing.T) {
+	models := NewModels(testdb.New(t))
+	ctx := context.Background()
+
+	name := "Casa"
+	old := Store{Name: &name}
+
+	if err := models.Stores.Insert(ctx, &old, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Stores.Delete(ctx, *old.ID, "user", old.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	// The trashed store no longer holds its name.
+	recreated := Store{Name: &name}
+
+	if err := models.Stores.Insert(ctx, &recreated, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := models.Stores.Restore(ctx, *old.ID, "user")
+
+	var clash *DuplicateNameError
+
+	if !errors.As(err, &clash) {
+		t.Fatalf("got %v, want a DuplicateNameError", err)
+	}
+
+	if clash.ExistingId != *recreated.ID {
+		t.Errorf("got existing id %d, want %d", clash.ExistingId, *recreated.ID)
+	}
+}
@@ -0,0 +1,386 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	EventItemCreated  = "item.created"
+	EventItemUpdated  = "item.updated"
+	EventItemLowStock = "item.low_stock"
+	EventEatenCreated = "eaten.created"
+	EventStoreDeleted = "store.deleted"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type WebhookModel struct {
+	DB *pgxpool.Pool
+}
+
+type Webhook struct {
+	Id         *int       `json:"id,omitempty"`
+	UserId     string     `json:"-"`
+	StoreId    *int       `json:"store_id"`
+	URL        *string    `json:"url,omitempty" validate:"required,http_url"`
+	Secret     *string    `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Events     []string   `json:"events" validate:"required,min=1,dive,oneof=item.created item.updated item.low_stock eaten.created store.deleted"`
+	Active     *bool      `json:"active,omitempty"`
+	Version    *string    `json:"version"`
+	CreatedAt  *time.Time `json:"created_at"`
+	ModifiedAt *time.Time `json:"modified_at"`
+}
+
+type UpdateWebhook struct {
+	Id      *int     `json:"id,omitempty" validate:"required"`
+	URL     *string  `json:"url,omitempty" validate:"omitempty,http_url"`
+	Secret  *string  `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Events  []string `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=item.created item.updated item.low_stock eaten.created store.deleted"`
+	Active  *bool    `json:"active,omitempty"`
+	Version *string  `json:"version" validate:"required"`
+}
+
+type DeliveryAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code"`
+	Error       *string   `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+}
+
+type Delivery struct {
+	Id             int               `json:"id"`
+	SubscriptionId int               `json:"subscription_id"`
+	Event          string            `json:"event"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at"`
+	Log            []DeliveryAttempt `json:"log,omitempty"`
+
+	// Filled in when the delivery is claimed for sending.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+func (m WebhookModel) Insert(ctx context.Context, webhook *Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO webhook_subscriptions(user_id, store_id, url, secret, events)
+			 VALUES ($1, $2, $3, $4, $5)
+			 RETURNING id, active, version, created_at`
+
+	args := []interface{}{webhook.UserId, webhook.StoreId, *webhook.URL, *webhook.Secret, webhook.Events}
+
+	return dbError(m.DB.QueryRow(ctx, stmt, args...).Scan(&webhook.Id, &webhook.Active, &webhook.Version, &webhook.CreatedAt))
+}
+
+func (m WebhookModel) List(ctx context.Context, userId string) (webhooks []Webhook, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, user_id, store_id, url, events, active, version, created_at, modified_at
+			 FROM webhook_subscriptions
+			 WHERE user_id = $1
+			 ORDER BY id`
+
+	rows, err := m.DB.Query(ctx, stmt, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var webhook Webhook
+
+		err := rows.Scan(&webhook.Id, &webhook.UserId, &webhook.StoreId, &webhook.URL, &webhook.Events, &webhook.Active, &webhook.Version, &webhook.CreatedAt, &webhook.ModifiedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Get returns the subscription without its secret.
+func (m WebhookModel) Get(ctx context.Context, webhookId int, userId string) (webhook Webhook, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `SELECT id, user_id, store_id, url, events, active, version, created_at, modified_at
+			 FROM webhook_subscriptions
+			 WHERE id = $1 AND user_id = $2`
+
+	err = m.DB.QueryRow(ctx, stmt, webhookId, userId).Scan(&webhook.Id, &webhook.UserId, &webhook.StoreId, &webhook.URL, &webhook.Events, &webhook.Active, &webhook.Version, &webhook.CreatedAt, &webhook.ModifiedAt)
+
+	if err != nil {
+		return Webhook{}, dbError(err)
+	}
+
+	return webhook, nil
+}
+
+// Update changes the subscription; a nil secret keeps the current one.
+func (m WebhookModel) Update(ctx context.Context, webhook *Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE webhook_subscriptions
+			 SET url = $1, secret = COALESCE($2, secret), events = $3, active = $4, version = uuid_generate_v4(), modified_at = NOW()
+			 WHERE id = $5 AND user_id = $6 AND version = $7
+			 RETURNING version, created_at, modified_at`
+
+	args := []interface{}{*webhook.URL, webhook.Secret, webhook.Events, *webhook.Active, *webhook.Id, webhook.UserId, *webhook.Version}
+
+	return updateError(m.DB.QueryRow(ctx, stmt, args...).Scan(&webhook.Version, &webhook.CreatedAt, &webhook.ModifiedAt))
+}
+
+func (m WebhookModel) Delete(ctx context.Context, webhookId int, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `DELETE FROM webhook_subscriptions
+			 WHERE id = $1 AND user_id = $2`
+
+	result, err := m.DB.Exec(ctx, stmt, webhookId, userId)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// Enqueue queues a delivery of the event for every active subscription of
+// the user that listens to it, either on every store or on this one.
+func (m WebhookModel) Enqueue(ctx context.Context, userId string, storeId int, event string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `INSERT INTO webhook_deliveries(subscription_id, event, payload)
+			 SELECT id, $3, $4
+			 FROM webhook_subscriptions
+			 WHERE user_id = $1 AND active AND (store_id IS NULL OR store_id = $2) AND $3 = ANY(events)`
+
+	_, err := m.DB.Exec(ctx, stmt, userId, storeId, event, payload)
+
+	return err
+}
+
+// Claim locks up to limit due deliveries and pushes their next attempt a
+// lease into the future, so that no other instance picks them up while
+// they are being sent.
+func (m WebhookModel) Claim(ctx context.Context, limit int, lease time.Duration) (deliveries []Delivery, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = now() + make_interval(secs => $2)
+			FROM webhook_subscriptions s
+			WHERE s.id = d.subscription_id AND d.id IN (
+				SELECT id
+				FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= now()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING d.id, d.subscription_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at, d.delivered_at, s.url, s.secret
+	`
+
+	rows, err := tx.Query(ctx, stmt, limit, lease.Seconds())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var d Delivery
+
+		err := rows.Scan(&d.Id, &d.SubscriptionId, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, tx.Commit(ctx)
+}
+
+// RecordAttempt logs one attempt and moves the delivery to its next state:
+// delivered, failed for good, or pending until nextAttempt.
+func (m WebhookModel) RecordAttempt(ctx context.Context, deliveryId int, attempt DeliveryAttempt, status string, nextAttempt time.Time) error {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			INSERT INTO webhook_delivery_attempts(delivery_id, attempted_at, status_code, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = tx.Exec(ctx, stmt, deliveryId, attempt.AttemptedAt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	stmt = `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = attempts + 1, next_attempt_at = $3,
+				delivered_at = CASE WHEN $2 = 'delivered' THEN now() END
+			WHERE id = $1
+	`
+
+	_, err = tx.Exec(ctx, stmt, deliveryId, status, nextAttempt)
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListDeliveries returns the latest deliveries of a subscription, each with
+// its attempt log.
+func (m WebhookModel) ListDeliveries(ctx context.Context, webhookId int, limit int) (deliveries []Delivery, err error) {
+	tx, err := m.DB.Begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := `
+			SELECT id, subscription_id, event, payload, status, attempts, next_attempt_at, created_at, delivered_at
+			FROM webhook_deliveries
+			WHERE subscription_id = $1
+			ORDER BY id DESC
+			LIMIT $2
+	`
+
+	rows, err := tx.Query(ctx, stmt, webhookId, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	index := make(map[int]int)
+	var ids []int
+
+	for rows.Next() {
+		var d Delivery
+
+		err := rows.Scan(&d.Id, &d.SubscriptionId, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		index[d.Id] = len(deliveries)
+		ids = append(ids, d.Id)
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+			SELECT delivery_id, attempted_at, status_code, error, duration_ms
+			FROM webhook_delivery_attempts
+			WHERE delivery_id = ANY($1)
+			ORDER BY id
+	`
+
+	rows, err = tx.Query(ctx, stmt, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var deliveryId int
+		var a DeliveryAttempt
+
+		err := rows.Scan(&deliveryId, &a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		d := &deliveries[index[deliveryId]]
+		d.Log = append(d.Log, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, tx.Commit(ctx)
+}
+
+// Redeliver puts a delivery of the subscription back in the queue for an
+// immediate attempt, whatever its current state.
+func (m WebhookModel) Redeliver(ctx context.Context, deliveryId, webhookId int) (delivery Delivery, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE webhook_deliveries
+			 SET status = 'pending', next_attempt_at = now(), delivered_at = NULL, attempts = 0
+			 WHERE id = $1 AND subscription_id = $2
+			 RETURNING id, subscription_id, event, payload, status, attempts, next_attempt_at, created_at, delivered_at`
+
+	err = m.DB.QueryRow(ctx, stmt, deliveryId, webhookId).Scan(&delivery.Id, &delivery.SubscriptionId, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.DeliveredAt)
+
+	if err != nil {
+		return Delivery{}, dbError(err)
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,186 @@
+package filters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor, start again from the first page")
+
+// Column is a field that lists can be sorted by: the SQL expression and the
+// type its value is cast back to when read from a cursor.
+type Column struct {
+	Expr string
+	Type string
+}
+
+// Filters holds the sort and the page asked for in the query string.
+type Filters struct {
+	Sort         string
+	Desc         bool
+	PageSize     int `validate:"gte=1,lte=500"`
+	Cursor       *Cursor
+	SortSafelist []string
+}
+
+// Cursor points just past the last row of a page. It is handed out as an
+// opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	Id    int    `json:"i"`
+}
+
+// Metadata is sent alongside every page.
+type Metadata struct {
+	PageSize     int    `json:"page_size"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	TotalRecords int    `json:"total_records"`
+}
+
+// New reads sort, page_size and cursor. sort is one of the safelisted
+// fields, or "id" by default, with a leading "-" for descending order.
+func New(query url.Values, sortSafelist ...string) (filters Filters, err error) {
+	filters.SortSafelist = append([]string{"id"}, sortSafelist...)
+	filters.Sort = "id"
+	filters.PageSize = DefaultPageSize
+
+	if val := query.Get("sort"); val != "" {
+		filters.Desc = strings.HasPrefix(val, "-")
+		filters.Sort = strings.TrimPrefix(val, "-")
+
+		if !slices.Contains(filters.SortSafelist, filters.Sort) {
+			return Filters{}, fmt.Errorf("sort must be one of: %s", strings.Join(filters.SortSafelist, ", "))
+		}
+	}
+
+	if val := query.Get("page_size"); val != "" {
+		filters.PageSize, err = strconv.Atoi(val)
+
+		if err != nil {
+			return Filters{}, fmt.Errorf("page_size must be a number")
+		}
+	}
+
+	v := validator.New()
+	err = v.Struct(filters)
+
+	if err != nil {
+		return Filters{}, err
+	}
+
+	if val := query.Get("cursor"); val != "" {
+		filters.Cursor, err = decodeCursor(val)
+
+		// A cursor only makes sense for the order it was made with.
+		if err != nil || filters.Cursor.Sort != filters.Sort || filters.Cursor.Desc != filters.Desc {
+			return Filters{}, ErrInvalidCursor
+		}
+	}
+
+	return filters, nil
+}
+
+func decodeCursor(val string) (*Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(val)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor Cursor
+
+	err = json.Unmarshal(js, &cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// Keyset returns the pieces of a paginated query over columns: the
+// expression to select as the sort key, the condition that skips the rows
+// up to the cursor, the ORDER BY and LIMIT clauses and their arguments.
+// Placeholders are numbered from next. One row more than the page is
+// fetched so that NextCursor can tell whether there is another page.
+func (f Filters) Keyset(columns map[string]Column, next int) (sortKey, where, orderLimit string, args []any) {
+	column := columns[f.Sort]
+	direction, compare := "ASC", ">"
+
+	if f.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	sortKey = column.Expr + "::text"
+	where = "TRUE"
+
+	if f.Cursor != nil {
+		where = fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", column.Expr, compare, next, column.Type, next+1)
+		args = append(args, f.Cursor.Value, f.Cursor.Id)
+		next += 2
+	}
+
+	orderLimit = fmt.Sprintf("ORDER BY %s %s, id %s LIMIT $%d", column.Expr, direction, direction, next)
+	args = append(args, f.PageSize+1)
+
+	return sortKey, where, orderLimit, args
+}
+
+// NextCursor is given the number of rows fetched and the sort key and id of
+// the last row of the page. It returns "" when there is no next page.
+func (f Filters) NextCursor(fetched int, sortKey string, id int) string {
+	if fetched <= f.PageSize {
+		return ""
+	}
+
+	js, _ := json.Marshal(Cursor{Sort: f.Sort, Desc: f.Desc, Value: sortKey, Id: id})
+
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// Range is an inclusive range given as min_<name> and max_<name>.
+type Range struct {
+	Min *int
+	Max *int
+}
+
+func NewRange(query url.Values, name string) (r Range, err error) {
+	for _, bound := range []struct {
+		key string
+		dst **int
+	}{{"min_" + name, &r.Min}, {"max_" + name, &r.Max}} {
+		val := query.Get(bound.key)
+
+		if val == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(val)
+
+		if err != nil {
+			return Range{}, fmt.Errorf("%s must be a number", bound.key)
+		}
+
+		*bound.dst = &n
+	}
+
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return Range{}, fmt.Errorf("min_%s must not be greater than max_%s", name, name)
+	}
+
+	return r, nil
+}
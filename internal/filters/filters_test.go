@@ -0,0 +1,193 @@
+package filters
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var columns = map[string]Column{
+	"id":   {Expr: "id", Type: "int"},
+	"name": {Expr: "name", Type: "text"},
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		sort     string
+		desc     bool
+		pageSize int
+		wantErr  bool
+	}{
+		{name: "defaults", query: "", sort: "id", pageSize: DefaultPageSize},
+		{name: "ascending", query: "sort=name&page_size=10", sort: "name", pageSize: 10},
+		{name: "descending", query: "sort=-name", sort: "name", desc: true, pageSize: DefaultPageSize},
+		{name: "largest page", query: "page_size=500", sort: "id", pageSize: MaxPageSize},
+		{name: "unknown sort", query: "sort=capacity", wantErr: true},
+		{name: "page too large", query: "page_size=501", wantErr: true},
+		{name: "page too small", query: "page_size=0", wantErr: true},
+		{name: "page not a number", query: "page_size=ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+
+			got, err := New(query, "name")
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Sort != tt.sort || got.Desc != tt.desc || got.PageSize != tt.pageSize {
+				t.Errorf("got sort %q desc %v page size %d, want %q %v %d", got.Sort, got.Desc, got.PageSize, tt.sort, tt.desc, tt.pageSize)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	f, err := New(url.Values{"sort": {"-name"}, "page_size": {"2"}}, "name")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := f.NextCursor(2, "Pane", 7); got != "" {
+		t.Errorf("NextCursor on the last page = %q, want none", got)
+	}
+
+	cursor := f.NextCursor(3, "Pane", 7)
+
+	if cursor == "" {
+		t.Fatal("NextCursor with more rows returned none")
+	}
+
+	next, err := New(url.Values{"sort": {"-name"}, "page_size": {"2"}, "cursor": {cursor}}, "name")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Cursor{Sort: "name", Desc: true, Value: "Pane", Id: 7}
+
+	if !reflect.DeepEqual(next.Cursor, want) {
+		t.Errorf("cursor = %+v, want %+v", next.Cursor, want)
+	}
+
+	for name, query := range map[string]url.Values{
+		"other sort":      {"sort": {"id"}, "cursor": {cursor}},
+		"other direction": {"sort": {"name"}, "cursor": {cursor}},
+		"not base64":      {"sort": {"-name"}, "cursor": {"!!!"}},
+		"not json":        {"sort": {"-name"}, "cursor": {"bm90IGpzb24"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(query, "name"); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name       string
+		filters    Filters
+		next       int
+		where      string
+		orderLimit string
+		args       []any
+	}{
+		{
+			name:       "first page",
+			filters:    Filters{Sort: "name", PageSize: 10},
+			next:       3,
+			where:      "TRUE",
+			orderLimit: "ORDER BY name ASC, id ASC LIMIT $3",
+			args:       []any{11},
+		},
+		{
+			name:       "next page",
+			filters:    Filters{Sort: "name", PageSize: 10, Cursor: &Cursor{Sort: "name", Value: "Pane", Id: 7}},
+			next:       3,
+			where:      "(name, id) > ($3::text, $4)",
+			orderLimit: "ORDER BY name ASC, id ASC LIMIT $5",
+			args:       []any{"Pane", 7, 11},
+		},
+		{
+			name:       "next page descending",
+			filters:    Filters{Sort: "id", Desc: true, PageSize: 5, Cursor: &Cursor{Sort: "id", Desc: true, Value: "9", Id: 9}},
+			next:       1,
+			where:      "(id, id) < ($1::int, $2)",
+			orderLimit: "ORDER BY id DESC, id DESC LIMIT $3",
+			args:       []any{"9", 9, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortKey, where, orderLimit, args := tt.filters.Keyset(columns, tt.next)
+
+			if want := columns[tt.filters.Sort].Expr + "::text"; sortKey != want {
+				t.Errorf("sort key = %q, want %q", sortKey, want)
+			}
+
+			if where != tt.where {
+				t.Errorf("where = %q, want %q", where, tt.where)
+			}
+
+			if orderLimit != tt.orderLimit {
+				t.Errorf("order and limit = %q, want %q", orderLimit, tt.orderLimit)
+			}
+
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestNewRange(t *testing.T) {
+	tests := []struct {
+		query    string
+		min, max *int
+		wantErr  bool
+	}{
+		{query: ""},
+		{query: "min_capacity=2", min: ptr(2)},
+		{query: "min_capacity=2&max_capacity=5", min: ptr(2), max: ptr(5)},
+		{query: "max_capacity=0", max: ptr(0)},
+		{query: "min_capacity=5&max_capacity=2", wantErr: true},
+		{query: "min_capacity=two", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+
+			got, err := NewRange(query, "capacity")
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got.Min, tt.min) || !reflect.DeepEqual(got.Max, tt.max) {
+				t.Errorf("got %v-%v, want %v-%v", got.Min, got.Max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func ptr(n int) *int {
+	return &n
+}
@@ -0,0 +1,126 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize lowercases s, strips accents and collapses everything that is not
+// a letter or a digit into single spaces.
+func Normalize(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	folded, _, err := transform.String(t, s)
+
+	if err != nil {
+		folded = s
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// Score returns how similar query and name are, from 0 (nothing in common)
+// to 1 (same name once normalized). It tolerates typos, plural endings and
+// extra words around the name, so "2 mozarella balls" still scores high
+// against "Mozzarella".
+func Score(query, name string) float64 {
+	q, n := Normalize(query), Normalize(name)
+
+	if q == "" || n == "" {
+		return 0
+	}
+
+	if q == n {
+		return 1
+	}
+
+	best := ratio(q, n)
+
+	if strings.Contains(" "+q+" ", " "+n+" ") || strings.Contains(" "+n+" ", " "+q+" ") {
+		best = max(best, 0.9)
+	}
+
+	nameTokens := strings.Fields(n)
+	queryTokens := strings.Fields(q)
+
+	// Every word of the item name has to show up, more or less, in the query.
+	total := 0.0
+
+	for _, nt := range nameTokens {
+		tokenBest := 0.0
+
+		for _, qt := range queryTokens {
+			tokenBest = max(tokenBest, ratio(qt, nt))
+		}
+
+		total += tokenBest
+	}
+
+	return max(best, 0.85*total/float64(len(nameTokens)))
+}
+
+// Best returns the index of the candidate that matches query best and its
+// score, or -1 when no candidate reaches threshold.
+func Best(query string, candidates []string, threshold float64) (int, float64) {
+	index, best := -1, 0.0
+
+	for i, candidate := range candidates {
+		score := Score(query, candidate)
+
+		if score > best {
+			index, best = i, score
+		}
+	}
+
+	if best < threshold {
+		return -1, best
+	}
+
+	return index, best
+}
@@ -0,0 +1,79 @@
+package fuzzy
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Mozzarella", "mozzarella"},
+		{"  Caffè  d'Orzo!! ", "caffe d orzo"},
+		{"Farina 00", "farina 00"},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := Normalize(tt.s); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		query, name string
+		min, max    float64
+	}{
+		{"mozzarella", "Mozzarella", 1, 1},
+		{"caffè", "Caffe", 1, 1},
+		{"farina 00", "Farina", 0.9, 0.9},
+		{"2 mozarella balls", "Mozzarella", 0.6, 0.8},
+		{"eggs", "egg", 0.6, 0.8},
+		{"prosciutto crudo", "Prosciutto cotto", 0.6, 0.85},
+		{"latte", "Pane", 0, 0.6},
+		{"", "Pane", 0, 0},
+		{"!!", "Pane", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+"/"+tt.name, func(t *testing.T) {
+			got := Score(tt.query, tt.name)
+
+			if got < tt.min || got > tt.max {
+				t.Errorf("Score(%q, %q) = %.3f, want between %.2f and %.2f", tt.query, tt.name, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	candidates := []string{"Pane", "Mozzarella", "Latte"}
+
+	tests := []struct {
+		query     string
+		threshold float64
+		want      int
+	}{
+		{"mozarela", 0.6, 1},
+		{"latte", 0.8, 2},
+		{"mozarela", 0.9, -1},
+		{"xyz", 0.6, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got, score := Best(tt.query, candidates, tt.threshold); got != tt.want {
+				t.Errorf("Best(%q, %.1f) = %d (score %.3f), want %d", tt.query, tt.threshold, got, score, tt.want)
+			}
+		})
+	}
+
+	if got, _ := Best("pane", nil, 0); got != -1 {
+		t.Errorf("Best with no candidates = %d, want -1", got)
+	}
+}
@@ -0,0 +1,224 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Piccio-Code/MealStore/internal/parse"
+	"regexp"
+	"strings"
+)
+
+var ErrNoRecipe = errors.New("the document does not contain a schema.org Recipe")
+
+// Recipe is the subset of a schema.org Recipe needed to check it against
+// the pantry.
+type Recipe struct {
+	Name        string       `json:"name"`
+	Yield       float64      `json:"yield,omitempty"`
+	Ingredients []Ingredient `json:"ingredients"`
+}
+
+// Ingredient is a recipeIngredient line split into its parts. Quantity is
+// nil when the line has none, e.g. "salt to taste".
+type Ingredient struct {
+	Line     string   `json:"line"`
+	Quantity *float64 `json:"quantity"`
+	Unit     string   `json:"unit,omitempty"`
+	Name     string   `json:"name"`
+}
+
+// Countable reports whether the ingredient is counted in pieces, which is
+// the only case comparable with an item's current capacity.
+func (i Ingredient) Countable() bool {
+	return i.Unit == "" || parse.Countable(i.Unit)
+}
+
+type node struct {
+	Type             interface{}       `json:"@type"`
+	Graph            []json.RawMessage `json:"@graph"`
+	Name             string            `json:"name"`
+	RecipeYield      interface{}       `json:"recipeYield"`
+	RecipeIngredient []string          `json:"recipeIngredient"`
+	Ingredients      []string          `json:"ingredients"`
+}
+
+func (n node) isRecipe() bool {
+	switch t := n.Type.(type) {
+	case string:
+		return t == "Recipe" || t == "schema:Recipe" || strings.HasSuffix(t, "schema.org/Recipe")
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && (node{Type: s}).isRecipe() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ParseRecipe finds the first Recipe node in a JSON-LD document, which may
+// be a single object, an array of objects or an object with an @graph.
+func ParseRecipe(doc json.RawMessage) (Recipe, error) {
+	var list []json.RawMessage
+
+	if err := json.Unmarshal(doc, &list); err == nil {
+		for _, raw := range list {
+			if recipe, err := ParseRecipe(raw); err == nil {
+				return recipe, nil
+			}
+		}
+
+		return Recipe{}, ErrNoRecipe
+	}
+
+	var n node
+
+	if err := json.Unmarshal(doc, &n); err != nil {
+		return Recipe{}, err
+	}
+
+	if !n.isRecipe() {
+		for _, raw := range n.Graph {
+			if recipe, err := ParseRecipe(raw); err == nil {
+				return recipe, nil
+			}
+		}
+
+		return Recipe{}, ErrNoRecipe
+	}
+
+	lines := n.RecipeIngredient
+
+	if len(lines) == 0 {
+		lines = n.Ingredients
+	}
+
+	recipe := Recipe{
+		Name:        n.Name,
+		Yield:       parseYield(n.RecipeYield),
+		Ingredients: make([]Ingredient, 0, len(lines)),
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		recipe.Ingredients = append(recipe.Ingredients, ParseIngredientLine(line))
+	}
+
+	return recipe, nil
+}
+
+func parseYield(v interface{}) float64 {
+	switch y := v.(type) {
+	case float64:
+		return y
+	case string:
+		if q, _, ok := parseQuantity(strings.Fields(parse.NormalizeFractions(y))); ok {
+			return q
+		}
+	case []interface{}:
+		for _, e := range y {
+			if q := parseYield(e); q > 0 {
+				return q
+			}
+		}
+	}
+
+	return 0
+}
+
+var numberUnit = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)([a-zA-Z]+)$`)
+
+// parseQuantity reads a leading quantity such as "2", "1.5", "1 1/2" or a
+// range like "2-3", of which the upper bound is kept. It returns the number
+// of tokens consumed.
+func parseQuantity(tokens []string) (float64, int, bool) {
+	if len(tokens) == 0 {
+		return 0, 0, false
+	}
+
+	first := tokens[0]
+
+	if low, high, ok := strings.Cut(first, "-"); ok && low != "" && high != "" {
+		if _, ok := parse.Number(low); ok {
+			first = high
+		}
+	}
+
+	q, ok := parse.Number(first)
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	used := 1
+
+	if len(tokens) > 1 && strings.Contains(tokens[1], "/") && !strings.Contains(first, "/") {
+		if f, ok := parse.Number(tokens[1]); ok {
+			q += f
+			used++
+		}
+	}
+
+	if len(tokens) > used+1 && (tokens[used] == "-" || tokens[used] == "to") {
+		if h, ok := parse.Number(tokens[used+1]); ok {
+			q = h
+			used += 2
+		}
+	}
+
+	return q, used, true
+}
+
+// ParseIngredientLine splits a line like "1 1/2 cups all-purpose flour,
+// sifted" into quantity 1.5, unit "cup" and name "all-purpose flour".
+func ParseIngredientLine(line string) Ingredient {
+	ingredient := Ingredient{Line: line}
+
+	text := parse.NormalizeFractions(line)
+
+	if i := strings.IndexAny(text, "(["); i >= 0 {
+		if j := strings.IndexAny(text[i:], ")]"); j >= 0 {
+			text = text[:i] + " " + text[i+j+1:]
+		}
+	}
+
+	tokens := strings.Fields(text)
+
+	if len(tokens) > 0 {
+		if m := numberUnit.FindStringSubmatch(tokens[0]); m != nil {
+			if _, ok := parse.Unit(m[2]); ok {
+				tokens = append([]string{m[1], m[2]}, tokens[1:]...)
+			}
+		}
+	}
+
+	if q, used, ok := parseQuantity(tokens); ok {
+		ingredient.Quantity = &q
+		tokens = tokens[used:]
+	}
+
+	if len(tokens) > 0 {
+		if unit, ok := parse.Unit(tokens[0]); ok && len(tokens) > 1 {
+			ingredient.Unit = unit
+			tokens = tokens[1:]
+		}
+	}
+
+	if len(tokens) > 0 && (strings.EqualFold(tokens[0], "of") || strings.EqualFold(tokens[0], "di")) {
+		tokens = tokens[1:]
+	}
+
+	name := strings.Join(tokens, " ")
+
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+
+	ingredient.Name = strings.TrimSpace(name)
+
+	return ingredient
+}
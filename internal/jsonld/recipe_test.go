@@ -0,0 +1,104 @@
+package jsonld
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseIngredientLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		quantity float64
+		unit     string
+		name     string
+	}{
+		{"1 1/2 cups all-purpose flour, sifted", 1.5, "cup", "all-purpose flour"},
+		{"½ tsp salt", 0.5, "tsp", "salt"},
+		{"2-3 cloves garlic", 3, "clove", "garlic"},
+		{"2 to 3 eggs", 3, "", "eggs"},
+		{"500g di farina", 500, "g", "farina"},
+		{"1 (14 oz) can tomatoes", 1, "can", "tomatoes"},
+		{"3 eggs", 3, "", "eggs"},
+		{"1 cup", 1, "", "cup"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := ParseIngredientLine(tt.line)
+
+			if got.Quantity == nil || *got.Quantity != tt.quantity || got.Unit != tt.unit || got.Name != tt.name {
+				t.Errorf("got %+v, want quantity %v, unit %q, name %q", got, tt.quantity, tt.unit, tt.name)
+			}
+		})
+	}
+
+	if got := ParseIngredientLine("salt to taste"); got.Quantity != nil || got.Name != "salt to taste" {
+		t.Errorf("got %+v, want no quantity and the whole line as name", got)
+	}
+}
+
+func TestParseRecipe(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		recipe      string
+		yield       float64
+		ingredients int
+		err         error
+	}{
+		{
+			name:        "single object",
+			doc:         `{"@type": "Recipe", "name": "Pancakes", "recipeYield": "4 servings", "recipeIngredient": ["2 eggs", "", "200 g flour"]}`,
+			recipe:      "Pancakes",
+			yield:       4,
+			ingredients: 2,
+		},
+		{
+			name:        "array with a type list",
+			doc:         `[{"@type": "WebPage"}, {"@type": ["Thing", "Recipe"], "name": "Soup", "recipeYield": 6, "ingredients": ["1 onion"]}]`,
+			recipe:      "Soup",
+			yield:       6,
+			ingredients: 1,
+		},
+		{
+			name:        "graph",
+			doc:         `{"@context": "https://schema.org", "@graph": [{"@type": "Person"}, {"@type": "http://schema.org/Recipe", "name": "Pizza", "recipeYield": ["2", "2 pizzas"], "recipeIngredient": ["1 pizza dough"]}]}`,
+			recipe:      "Pizza",
+			yield:       2,
+			ingredients: 1,
+		},
+		{
+			name:   "unreadable yield",
+			doc:    `{"@type": "schema:Recipe", "name": "Stew", "recipeYield": "serves many"}`,
+			recipe: "Stew",
+		},
+		{
+			name: "no recipe",
+			doc:  `{"@graph": [{"@type": "Person"}]}`,
+			err:  ErrNoRecipe,
+		},
+		{
+			name: "empty array",
+			doc:  `[]`,
+			err:  ErrNoRecipe,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecipe([]byte(tt.doc))
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			if got.Name != tt.recipe || got.Yield != tt.yield || len(got.Ingredients) != tt.ingredients {
+				t.Errorf("got %q yield %v with %d ingredients, want %q yield %v with %d", got.Name, got.Yield, len(got.Ingredients), tt.recipe, tt.yield, tt.ingredients)
+			}
+		})
+	}
+
+	if _, err := ParseRecipe([]byte(`not json`)); err == nil {
+		t.Error("invalid JSON returned no error")
+	}
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+type Message struct {
+	ChatId string
+	Text   string
+
+	// Keyboard is an optional inline keyboard, one slice per row. Senders
+	// that cannot show buttons ignore it.
+	Keyboard [][]Button
+}
+
+// Button is an inline keyboard button; Data is sent back when pressed.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data"`
+}
+
+// Notifier delivers a message to a user.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Log writes messages to a logger instead of delivering them; it stands in
+// for a real sender when none is configured.
+type Log struct {
+	Logger *log.Logger
+}
+
+func (l Log) Send(ctx context.Context, msg Message) error {
+	l.Logger.Printf("notify %s: %s", msg.ChatId, msg.Text)
+
+	return nil
+}
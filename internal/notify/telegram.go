@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// Telegram sends messages through the Telegram Bot API. BaseURL can point
+// to a local fake server.
+type Telegram struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+func NewTelegram(baseURL, token string) *Telegram {
+	if baseURL == "" {
+		baseURL = DefaultTelegramURL
+	}
+
+	return &Telegram{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type telegramResponse struct {
+	Ok          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Call invokes a Bot API method with a JSON payload.
+func (t *Telegram) Call(ctx context.Context, method string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", t.BaseURL, t.Token, method)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := t.Client.Do(req)
+
+	// The request URL carries the bot token, keep it out of the error.
+	var urlErr *neturl.Error
+
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("telegram %s: %w", method, urlErr.Err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	var result telegramResponse
+
+	err = json.NewDecoder(res.Body).Decode(&result)
+
+	if err != nil {
+		return fmt.Errorf("telegram %s: status %d: %w", method, res.StatusCode, err)
+	}
+
+	if !result.Ok {
+		return fmt.Errorf("telegram %s: status %d: %s", method, res.StatusCode, result.Description)
+	}
+
+	return nil
+}
+
+func (t *Telegram) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"chat_id": msg.ChatId,
+		"text":    msg.Text,
+	}
+
+	if len(msg.Keyboard) > 0 {
+		payload["reply_markup"] = map[string]interface{}{"inline_keyboard": msg.Keyboard}
+	}
+
+	return t.Call(ctx, "sendMessage", payload)
+}
+
+// AnswerCallback stops the loading indicator on a pressed inline button.
+func (t *Telegram) AnswerCallback(ctx context.Context, callbackId string) error {
+	return t.Call(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackId,
+	})
+}
@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type call struct {
+	path    string
+	payload map[string]interface{}
+}
+
+// fakeTelegram records every call and answers with reply.
+func fakeTelegram(t *testing.T, status int, reply string) (*Telegram, *[]call) {
+	t.Helper()
+
+	var calls []call
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		var payload map[string]interface{}
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+
+		calls = append(calls, call{r.URL.Path, payload})
+
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return NewTelegram(server.URL+"/", "123:secret"), &calls
+}
+
+func TestTelegramSend(t *testing.T) {
+	telegram, calls := fakeTelegram(t, http.StatusOK, `{"ok": true}`)
+
+	msg := Message{
+		ChatId:   "42",
+		Text:     "Running low: Latte in Casa (1 left)",
+		Keyboard: [][]Button{{{Text: "Add 1", Data: "/add #7 1"}}},
+	}
+
+	if err := telegram.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []call{{
+		path: "/bot123:secret/sendMessage",
+		payload: map[string]interface{}{
+			"chat_id": "42",
+			"text":    "Running low: Latte in Casa (1 left)",
+			"reply_markup": map[string]interface{}{
+				"inline_keyboard": []interface{}{[]interface{}{map[string]interface{}{"text": "Add 1", "callback_data": "/add #7 1"}}},
+			},
+		},
+	}}
+
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls = %+v, want %+v", *calls, want)
+	}
+}
+
+func TestTelegramSendWithoutKeyboard(t *testing.T) {
+	telegram, calls := fakeTelegram(t, http.StatusOK, `{"ok": true}`)
+
+	if err := telegram.Send(context.Background(), Message{ChatId: "42", Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := (*calls)[0].payload["reply_markup"]; ok {
+		t.Error("reply_markup sent without a keyboard")
+	}
+}
+
+func TestTelegramAnswerCallback(t *testing.T) {
+	telegram, calls := fakeTelegram(t, http.StatusOK, `{"ok": true}`)
+
+	if err := telegram.AnswerCallback(context.Background(), "cb-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []call{{
+		path:    "/bot123:secret/answerCallbackQuery",
+		payload: map[string]interface{}{"callback_query_id": "cb-1"},
+	}}
+
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls = %+v, want %+v", *calls, want)
+	}
+}
+
+func TestTelegramErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reply  string
+		want   string
+	}{
+		{"refused", http.StatusBadRequest, `{"ok": false, "description": "Bad Request: chat not found"}`, "status 400: Bad Request: chat not found"},
+		{"not json", http.StatusBadGateway, `<html>`, "status 502"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telegram, _ := fakeTelegram(t, tt.status, tt.reply)
+
+			err := telegram.Send(context.Background(), Message{ChatId: "42", Text: "hi"})
+
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	err := NewTelegram(server.URL, "123:secret").Send(context.Background(), Message{ChatId: "42", Text: "hi"})
+
+	if err == nil {
+		t.Fatal("got no error from a closed server")
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("err = %q, leaks the token", err)
+	}
+}
+
+func TestNewTelegramDefaultURL(t *testing.T) {
+	if got := NewTelegram("", "token").BaseURL; got != DefaultTelegramURL {
+		t.Errorf("BaseURL = %q, want %q", got, DefaultTelegramURL)
+	}
+}
@@ -0,0 +1,300 @@
+package parse
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Actions a sentence can describe.
+const (
+	ActionEat = "eat"
+	ActionBuy = "buy"
+)
+
+// Entry is one item mentioned in a sentence, e.g. "half a pizza".
+type Entry struct {
+	Text     string  `json:"text"`
+	Action   string  `json:"action,omitempty"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+	Name     string  `json:"name"`
+
+	// Implied is set when no quantity was written and 1 is assumed.
+	Implied bool `json:"quantity_implied"`
+
+	// Confidence is how sure the parser is about the quantity and the name,
+	// from 0 to 1. It says nothing about whether the name is a known item.
+	Confidence float64 `json:"confidence"`
+}
+
+// Countable reports whether the quantity is a number of pieces.
+func (e Entry) Countable() bool {
+	return Countable(e.Unit)
+}
+
+var verbs = map[string]string{
+	"ate": ActionEat, "eaten": ActionEat, "eat": ActionEat, "had": ActionEat, "drank": ActionEat, "used": ActionEat, "finished": ActionEat,
+	"mangiato": ActionEat, "mangiata": ActionEat, "mangiati": ActionEat, "mangiate": ActionEat, "bevuto": ActionEat, "usato": ActionEat, "finito": ActionEat,
+	"bought": ActionBuy, "buy": ActionBuy, "got": ActionBuy, "added": ActionBuy, "restocked": ActionBuy,
+	"comprato": ActionBuy, "comprati": ActionBuy, "comprata": ActionBuy, "comprate": ActionBuy, "preso": ActionBuy, "presi": ActionBuy, "aggiunto": ActionBuy,
+}
+
+// fillers are skipped in front of a verb: "I just ate", "ho appena comprato".
+var fillers = map[string]bool{
+	"i": true, "i've": true, "ive": true, "we": true, "have": true, "just": true, "today": true, "also": true,
+	"ho": true, "abbiamo": true, "appena": true, "oggi": true, "anche": true, "io": true,
+}
+
+var separators = map[string]bool{
+	",": true, ";": true, "and": true, "plus": true, "&": true, "+": true, "e": true, "ed": true, "poi": true,
+}
+
+var numberWords = map[string]float64{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"uno": 1, "due": 2, "tre": 3, "quattro": 4, "cinque": 5, "sei": 6,
+	"sette": 7, "otto": 8, "nove": 9, "dieci": 10, "undici": 11, "dodici": 12,
+}
+
+// indefinite articles count as one unless a quantity came before them, as
+// in "half a pizza".
+var indefinite = map[string]bool{
+	"a": true, "an": true, "un": true, "un'": true, "una": true,
+}
+
+var halves = map[string]bool{"half": true, "mezzo": true, "mezza": true}
+var dozens = map[string]bool{"dozen": true, "dozens": true, "dozzina": true, "dozzine": true}
+var pairs = map[string]bool{"couple": true, "pair": true, "paio": true}
+
+// connectives sit between a quantity or a unit and the name.
+var connectives = map[string]bool{
+	"of": true, "di": true, "d'": true, "de": true,
+}
+
+var articles = map[string]bool{
+	"the": true, "some": true, "il": true, "lo": true, "la": true, "i": true, "gli": true, "le": true, "l'": true,
+	"del": true, "dello": true, "della": true, "dei": true, "degli": true, "delle": true, "dell'": true,
+}
+
+var (
+	numberUnit = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)([a-z]+)$`)
+	times      = regexp.MustCompile(`^(?:x(\d+)|(\d+)x)$`)
+
+	// A comma followed by a space separates items; one between digits is
+	// a decimal point.
+	listComma = regexp.MustCompile(`[,;](\s|$)`)
+)
+
+func tokenize(text string) []string {
+	text = strings.ToLower(NormalizeFractions(text))
+	text = strings.NewReplacer("’", "'", "!", " ", "?", " ", ".\n", " ", "\n", " , ").Replace(text)
+	text = listComma.ReplaceAllString(text, " , $1")
+	text = strings.ReplaceAll(text, "'", "' ")
+
+	var tokens []string
+
+	for _, token := range strings.Fields(text) {
+		token = strings.TrimSuffix(token, ".")
+
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// Parse splits free text such as "ate 2 eggs and half a pizza" or "ho
+// comprato 3 latte, 500g di farina" into entries. Each entry keeps the
+// action of the last verb seen before it.
+func Parse(text string) []Entry {
+	var entries []Entry
+	var part []string
+	action := ""
+
+	flush := func() {
+		if entry, ok := parsePart(part, &action); ok {
+			entries = append(entries, entry)
+		}
+
+		part = nil
+	}
+
+	for _, token := range tokenize(text) {
+		if separators[token] {
+			flush()
+			continue
+		}
+
+		part = append(part, token)
+	}
+
+	flush()
+
+	return entries
+}
+
+func parsePart(tokens []string, action *string) (Entry, bool) {
+	// Leading "I just ate" or "ho comprato".
+	for len(tokens) > 0 {
+		if a, ok := verbs[tokens[0]]; ok {
+			*action = a
+		} else if !fillers[tokens[0]] {
+			break
+		}
+
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 {
+		return Entry{}, false
+	}
+
+	entry := Entry{Text: strings.ReplaceAll(strings.Join(tokens, " "), "' ", "'"), Action: *action, Confidence: 1}
+
+	if m := numberUnit.FindStringSubmatch(tokens[0]); m != nil {
+		if _, ok := Unit(m[2]); ok {
+			tokens = append([]string{m[1], m[2]}, tokens[1:]...)
+		}
+	}
+
+	quantity, used, words := leadingQuantity(tokens)
+	tokens = tokens[used:]
+
+	if used == 0 {
+		quantity, used = trailingQuantity(tokens)
+		tokens = tokens[:len(tokens)-used]
+	}
+
+	if len(tokens) > 1 {
+		if unit, ok := Unit(tokens[0]); ok {
+			entry.Unit = unit
+			tokens = tokens[1:]
+		}
+	}
+
+	for len(tokens) > 1 && (connectives[tokens[0]] || articles[tokens[0]]) {
+		tokens = tokens[1:]
+	}
+
+	entry.Name = strings.Trim(strings.Join(tokens, " "), " '\"")
+
+	if entry.Name == "" {
+		return Entry{}, false
+	}
+
+	if quantity <= 0 {
+		quantity = 1
+		entry.Implied = true
+		entry.Confidence *= 0.8
+	} else if words {
+		// "a" and "un" are also plain articles.
+		entry.Confidence *= 0.95
+	}
+
+	// Long names are usually a sentence the parser did not understand.
+	if n := len(tokens); n > 3 {
+		entry.Confidence *= math.Max(0.3, 1-0.15*float64(n-3))
+	}
+
+	entry.Quantity = quantity
+	entry.Confidence = math.Round(entry.Confidence*100) / 100
+
+	return entry, true
+}
+
+// leadingQuantity reads "2", "1 1/2", "2x", "half a", "a dozen", "un paio di"
+// and similar from the front of tokens. It returns the quantity, the number
+// of tokens used and whether it was spelled in words.
+func leadingQuantity(tokens []string) (quantity float64, used int, words bool) {
+	for used < len(tokens) {
+		token := tokens[used]
+
+		switch {
+		case quantity == 0 && times.MatchString(token):
+			m := times.FindStringSubmatch(token)
+			quantity, _ = Number(m[1] + m[2])
+		case isNumber(token):
+			n, _ := Number(token)
+
+			// "1 1/2" adds the fraction, any other number starts the name.
+			if quantity != 0 && !(strings.Contains(token, "/") && quantity == math.Trunc(quantity)) {
+				return quantity, used, words
+			}
+
+			quantity += n
+		case numberWords[token] > 0 && quantity == 0:
+			quantity = numberWords[token]
+			words = true
+		case indefinite[token]:
+			if quantity == 0 {
+				quantity = 1
+				words = true
+			}
+		case halves[token]:
+			if quantity == 0 || (quantity == 1 && words) {
+				quantity = 0.5
+			} else {
+				quantity += 0.5
+			}
+
+			words = true
+		case dozens[token]:
+			quantity = multiply(quantity, 12)
+			words = true
+		case pairs[token]:
+			quantity = multiply(quantity, 2)
+			words = true
+		case connectives[token] && quantity > 0:
+		default:
+			return quantity, used, words
+		}
+
+		used++
+	}
+
+	return quantity, used, words
+}
+
+// multiply applies "dozen" or "pair" to the quantity read so far: none
+// means one, and "half a dozen" is six.
+func multiply(quantity, by float64) float64 {
+	if quantity == 0 {
+		return by
+	}
+
+	return quantity * by
+}
+
+// trailingQuantity reads "eggs 2" or "eggs x2".
+func trailingQuantity(tokens []string) (float64, int) {
+	if len(tokens) < 2 {
+		return 0, 0
+	}
+
+	last := tokens[len(tokens)-1]
+
+	if m := times.FindStringSubmatch(last); m != nil {
+		last = m[1] + m[2]
+	}
+
+	if isNumber(last) {
+		if n, _ := Number(last); n > 0 {
+			return n, 1
+		}
+	}
+
+	return 0, 0
+}
+
+// isNumber is Number without "inf" and "nan", which are valid floats.
+func isNumber(token string) bool {
+	if token == "" || token[0] < '0' || token[0] > '9' {
+		return false
+	}
+
+	_, ok := Number(token)
+
+	return ok
+}
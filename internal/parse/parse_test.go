@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Entry
+	}{
+		{"ate 2 eggs and half a pizza", []Entry{
+			{Action: ActionEat, Quantity: 2, Name: "eggs"},
+			{Action: ActionEat, Quantity: 0.5, Name: "pizza"},
+		}},
+		{"comprato 3 latte", []Entry{
+			{Action: ActionBuy, Quantity: 3, Name: "latte"},
+		}},
+		{"ho comprato 3 latte, 500g di farina", []Entry{
+			{Action: ActionBuy, Quantity: 3, Name: "latte"},
+			{Action: ActionBuy, Quantity: 500, Unit: "g", Name: "farina"},
+		}},
+		{"ate 2 eggs, bought 3 milk", []Entry{
+			{Action: ActionEat, Quantity: 2, Name: "eggs"},
+			{Action: ActionBuy, Quantity: 3, Name: "milk"},
+		}},
+		{"ho mangiato una mela e due banane", []Entry{
+			{Action: ActionEat, Quantity: 1, Name: "mela"},
+			{Action: ActionEat, Quantity: 2, Name: "banane"},
+		}},
+		{"I just ate an apple", []Entry{{Action: ActionEat, Quantity: 1, Name: "apple"}}},
+		{"ate 1 1/2 pizzas", []Entry{{Action: ActionEat, Quantity: 1.5, Name: "pizzas"}}},
+		{"ate 1½ pizzas", []Entry{{Action: ActionEat, Quantity: 1.5, Name: "pizzas"}}},
+		{"3/4 cake", []Entry{{Quantity: 0.75, Name: "cake"}}},
+		{"mezzo panino", []Entry{{Quantity: 0.5, Name: "panino"}}},
+		{"mezza pizza", []Entry{{Quantity: 0.5, Name: "pizza"}}},
+		{"mezzo kg di farina", []Entry{{Quantity: 0.5, Unit: "kg", Name: "farina"}}},
+		{"a dozen eggs", []Entry{{Quantity: 12, Name: "eggs"}}},
+		{"two dozen eggs", []Entry{{Quantity: 24, Name: "eggs"}}},
+		{"half a dozen eggs", []Entry{{Quantity: 6, Name: "eggs"}}},
+		{"una dozzina di uova", []Entry{{Quantity: 12, Name: "uova"}}},
+		{"a couple of apples", []Entry{{Quantity: 2, Name: "apples"}}},
+		{"un paio di uova", []Entry{{Quantity: 2, Name: "uova"}}},
+		{"bought 1,5 kg of flour", []Entry{{Action: ActionBuy, Quantity: 1.5, Unit: "kg", Name: "flour"}}},
+		{"3 tbsp of the sugar", []Entry{{Quantity: 3, Unit: "tbsp", Name: "sugar"}}},
+		{"eggs x2", []Entry{{Quantity: 2, Name: "eggs"}}},
+		{"eggs 2", []Entry{{Quantity: 2, Name: "eggs"}}},
+		{"2x eggs", []Entry{{Quantity: 2, Name: "eggs"}}},
+		{"bread", []Entry{{Quantity: 1, Name: "bread", Implied: true}}},
+		{"ate", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Parse(tt.text)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d entries %+v, want %d", len(got), got, len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				g := got[i]
+
+				if g.Action != want.Action || g.Quantity != want.Quantity || g.Unit != want.Unit || g.Name != want.Name || g.Implied != want.Implied {
+					t.Errorf("entry %d = {action %q, quantity %v, unit %q, name %q, implied %v}, want {action %q, quantity %v, unit %q, name %q, implied %v}",
+						i, g.Action, g.Quantity, g.Unit, g.Name, g.Implied, want.Action, want.Quantity, want.Unit, want.Name, want.Implied)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"2 eggs", 1},
+		{"half a pizza", 0.95},
+		{"bread", 0.8},
+		{"ate some very long sentence that makes no sense at all", 0.24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Parse(tt.text)
+
+			if len(got) != 1 {
+				t.Fatalf("got %d entries, want 1", len(got))
+			}
+
+			if got[0].Confidence != tt.want {
+				t.Errorf("confidence = %v, want %v", got[0].Confidence, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+)
+
+var vulgarFractions = map[rune]string{
+	'¼': " 1/4", '½': " 1/2", '¾': " 3/4", '⅓': " 1/3", '⅔': " 2/3",
+	'⅛': " 1/8", '⅜': " 3/8", '⅝': " 5/8", '⅞': " 7/8",
+}
+
+// NormalizeFractions spells out characters such as "½" as " 1/2" so that
+// they can be read by Number.
+func NormalizeFractions(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if f, ok := vulgarFractions[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+
+		if r == '⁄' {
+			r = '/'
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Number reads "2", "1.5", "1,5" or "3/4".
+func Number(s string) (float64, bool) {
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+
+		return n / d, true
+	}
+
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+
+	return f, err == nil
+}
+
+// units maps every accepted spelling to its canonical unit.
+var units = map[string]string{
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g", "grammi": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "dl": "dl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l", "litro": "l", "litri": "l",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"cup": "cup", "cups": "cup", "tazza": "cup", "tazze": "cup",
+	"tbsp": "tbsp", "tbs": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp", "cucchiaio": "tbsp", "cucchiai": "tbsp",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp", "cucchiaino": "tsp", "cucchiaini": "tsp",
+	"pinch": "pinch", "pinches": "pinch", "pizzico": "pinch",
+	"clove": "clove", "cloves": "clove", "spicchio": "clove", "spicchi": "clove",
+	"slice": "slice", "slices": "slice", "fetta": "slice", "fette": "slice",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can", "lattina": "can", "lattine": "can",
+	"piece": "piece", "pieces": "piece", "pcs": "piece", "pz": "piece", "pezzo": "piece", "pezzi": "piece",
+	"pack": "pack", "packs": "pack", "package": "pack", "packages": "pack", "confezione": "pack", "confezioni": "pack",
+	"bunch": "bunch", "bunches": "bunch", "mazzo": "bunch",
+	"bottle": "bottle", "bottles": "bottle", "bottiglia": "bottle", "bottiglie": "bottle",
+	"glass": "glass", "glasses": "glass", "bicchiere": "glass", "bicchieri": "glass",
+	"box": "pack", "boxes": "pack", "scatola": "pack", "scatole": "pack",
+}
+
+// countUnits are the canonical units measured in whole pieces.
+var countUnits = map[string]bool{
+	"clove": true, "slice": true, "can": true, "piece": true, "pack": true, "bunch": true, "bottle": true,
+}
+
+// Unit returns the canonical unit for a spelling such as "Grams" or "tbsp.".
+func Unit(word string) (string, bool) {
+	unit, ok := units[strings.TrimSuffix(strings.ToLower(word), ".")]
+
+	return unit, ok
+}
+
+// Countable reports whether a canonical unit is counted in whole pieces;
+// no unit at all counts as pieces too.
+func Countable(unit string) bool {
+	return unit == "" || countUnits[unit]
+}
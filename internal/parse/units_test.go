@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"2", 2, true},
+		{"1.5", 1.5, true},
+		{"1,5", 1.5, true},
+		{"3/4", 0.75, true},
+		{"1/0", 0, false},
+		{"a/2", 0, false},
+		{"eggs", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := Number(tt.s)
+
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Number(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUnit(t *testing.T) {
+	tests := []struct {
+		word      string
+		want      string
+		ok        bool
+		countable bool
+	}{
+		{"Grams", "g", true, false},
+		{"tbsp.", "tbsp", true, false},
+		{"cucchiaini", "tsp", true, false},
+		{"fette", "slice", true, true},
+		{"box", "pack", true, true},
+		{"eggs", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			got, ok := Unit(tt.word)
+
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Unit(%q) = %q, %v, want %q, %v", tt.word, got, ok, tt.want, tt.ok)
+			}
+
+			if Countable(got) != tt.countable {
+				t.Errorf("Countable(%q) = %v, want %v", got, !tt.countable, tt.countable)
+			}
+		})
+	}
+}
+
+func TestNormalizeFractions(t *testing.T) {
+	if got, want := NormalizeFractions("1½ cups, ¾ l, 1⁄3"), "1 1/2 cups,  3/4 l, 1/3"; got != want {
+		t.Errorf("NormalizeFractions = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "* 24 * * *"},
+		{"day of month zero", "* * 0 * *"},
+		{"month out of range", "* * * 13 *"},
+		{"day of week out of range", "* * * * 8"},
+		{"reversed range", "* 10-5 * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"bad step", "*/x * * * *"},
+		{"bad value", "a * * * *"},
+		{"bad range end", "1-x * * * *"},
+		{"unknown descriptor", "@often"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.spec); err == nil {
+				t.Errorf("Parse(%q) returned no error", tt.spec)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return parsed
+	}
+
+	// 2026-10-19 is a Monday.
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"every minute", "* * * * *", "2026-10-19 10:00", "2026-10-19 10:01"},
+		{"strictly after", "30 10 * * *", "2026-10-19 10:30", "2026-10-20 10:30"},
+		{"seconds are dropped", "* * * * *", "2026-10-19 10:00", "2026-10-19 10:01"},
+		{"minute step", "*/15 * * * *", "2026-10-19 10:16", "2026-10-19 10:30"},
+		{"minute step wraps the hour", "*/15 * * * *", "2026-10-19 10:50", "2026-10-19 11:00"},
+		{"step from a start", "5/20 * * * *", "2026-10-19 10:26", "2026-10-19 10:45"},
+		{"stepped range", "0 8-20/4 * * *", "2026-10-19 12:01", "2026-10-19 16:00"},
+		{"hour range ends", "0 8-10 * * *", "2026-10-19 10:01", "2026-10-20 08:00"},
+		{"list", "0,20,40 * * * *", "2026-10-19 10:21", "2026-10-19 10:40"},
+		{"weekdays", "0 9 * * 1-5", "2026-10-23 09:00", "2026-10-26 09:00"},
+		{"sunday as 0", "0 0 * * 0", "2026-10-19 10:00", "2026-10-25 00:00"},
+		{"sunday as 7", "0 0 * * 7", "2026-10-19 10:00", "2026-10-25 00:00"},
+		{"day of month", "0 0 15 * *", "2026-10-19 10:00", "2026-11-15 00:00"},
+		{"both days restricted match either", "0 0 1 * 5", "2026-10-19 10:00", "2026-10-23 00:00"},
+		{"both days restricted match the date", "0 0 1 * 5", "2026-10-30 10:00", "2026-11-01 00:00"},
+		{"stepped day of month with weekday", "0 0 */2 * 1", "2026-10-19 10:00", "2026-11-09 00:00"},
+		{"day of month with stepped weekday", "0 0 13 * */2", "2026-10-14 00:00", "2026-12-13 00:00"},
+		{"month rollover", "0 0 * * *", "2026-10-31 23:59", "2026-11-01 00:00"},
+		{"year rollover", "0 0 1 1 *", "2026-10-19 10:00", "2027-01-01 00:00"},
+		{"skips short months", "0 0 31 * *", "2026-10-31 00:00", "2026-12-31 00:00"},
+		{"leap day", "0 0 29 2 *", "2026-10-19 10:00", "2028-02-29 00:00"},
+		{"month step", "0 0 1 */3 *", "2026-10-19 10:00", "2027-01-01 00:00"},
+		{"daily descriptor", "@daily", "2026-10-19 10:00", "2026-10-20 00:00"},
+		{"weekly descriptor", "@weekly", "2026-10-19 10:00", "2026-10-25 00:00"},
+		{"monthly descriptor", "@monthly", "2026-10-19 10:00", "2026-11-01 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+
+			from := at(tt.from)
+
+			if tt.name == "seconds are dropped" {
+				from = from.Add(42 * time.Second)
+			}
+
+			got := schedule.Next(from)
+
+			if want := at(tt.want); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next = %s, want the zero time", got)
+	}
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Piccio-Code/MealStore/internal/data"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("the job is already running on some instance")
+)
+
+type JobFunc func(ctx context.Context) error
+
+type Job struct {
+	Name     string    `json:"name"`
+	Spec     string    `json:"spec"`
+	Timeout  string    `json:"timeout"`
+	NextRun  time.Time `json:"next_run"`
+	schedule Schedule
+	timeout  time.Duration
+	run      JobFunc
+}
+
+// Scheduler runs registered jobs on their cron schedule. Every run is
+// recorded in job_runs and guarded by a Postgres advisory lock, so when
+// several instances share a database each tick runs on only one of them.
+type Scheduler struct {
+	runs     data.JobRunModel
+	infoLog  *log.Logger
+	errorLog *log.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func New(runs data.JobRunModel, infoLog, errorLog *log.Logger) *Scheduler {
+	return &Scheduler{
+		runs:     runs,
+		infoLog:  infoLog,
+		errorLog: errorLog,
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// Register adds a job; timeout bounds a single run.
+func (s *Scheduler) Register(name, spec string, timeout time.Duration, run JobFunc) error {
+	schedule, err := Parse(spec)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %q is already registered", name)
+	}
+
+	s.jobs[name] = &Job{
+		Name:     name,
+		Spec:     spec,
+		Timeout:  timeout.String(),
+		NextRun:  schedule.Next(time.Now()),
+		schedule: schedule,
+		timeout:  timeout,
+		run:      run,
+	}
+
+	return nil
+}
+
+// Jobs returns the registered jobs sorted by name.
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	return jobs
+}
+
+// Start checks for due jobs until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+
+			for _, job := range s.jobs {
+				if job.NextRun.IsZero() || now.Before(job.NextRun) {
+					continue
+				}
+
+				scheduledFor := job.NextRun
+				job.NextRun = job.schedule.Next(now)
+
+				go func(job *Job) {
+					_, err := s.execute(ctx, job, data.TriggerSchedule, &scheduledFor)
+
+					if err != nil && !errors.Is(err, ErrJobRunning) {
+						s.errorLog.Printf("job %s: %v", job.Name, err)
+					}
+				}(job)
+			}
+
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Trigger runs the job right away and waits for it to finish.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (data.JobRun, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return data.JobRun{}, ErrUnknownJob
+	}
+
+	return s.execute(ctx, job, data.TriggerManual, nil)
+}
+
+func (s *Scheduler) execute(ctx context.Context, job *Job, trigger string, scheduledFor *time.Time) (data.JobRun, error) {
+	release, ok, err := s.runs.TryLock(ctx, job.Name)
+
+	if err != nil {
+		return data.JobRun{}, err
+	}
+
+	if !ok {
+		return data.JobRun{}, ErrJobRunning
+	}
+
+	defer release()
+
+	run, ok, err := s.runs.Start(ctx, job.Name, trigger, scheduledFor)
+
+	if err != nil {
+		return data.JobRun{}, err
+	}
+
+	if !ok {
+		// Another instance already ran this tick.
+		return data.JobRun{}, ErrJobRunning
+	}
+
+	jobErr := s.safeRun(ctx, job)
+
+	if jobErr != nil {
+		s.errorLog.Printf("job %s failed: %v", job.Name, jobErr)
+	} else {
+		s.infoLog.Printf("job %s succeeded", job.Name)
+	}
+
+	err = s.runs.Finish(context.Background(), &run, jobErr)
+
+	return run, err
+}
+
+func (s *Scheduler) safeRun(ctx context.Context, job *Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.timeout)
+	defer cancel()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return job.run(ctx)
+}
@@ -0,0 +1,120 @@
+// Package testdb gives tests a database with every migration applied.
+// Tests that need one are skipped unless TEST_DB_DSN points to a database
+// they may write to. Each call works in a schema of its own, dropped when
+// the test ends, so tests never see each other's rows.
+package testdb
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// New returns a pool on a fresh schema that is closed and dropped once the
+// test and its subtests are done.
+func New(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	admin, err := pgx.Connect(ctx, dsn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+
+	_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := pgxpool.ParseConfig(dsn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Extensions already installed in public stay visible.
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+
+		if err != nil {
+			t.Error(err)
+		}
+
+		admin.Close(ctx)
+	})
+
+	err = migrate(ctx, pool)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+// migrate runs the Up part of every migration, in order.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	_, file, _, _ := runtime.Caller(0)
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(file), "..", "..", "migrations", "*.sql"))
+
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(files)
+
+	for _, name := range files {
+		content, err := os.ReadFile(name)
+
+		if err != nil {
+			return err
+		}
+
+		_, up, _ := strings.Cut(string(content), "-- +goose Up")
+		up, _, _ = strings.Cut(up, "-- +goose Down")
+
+		// Without arguments the whole file goes through the simple
+		// protocol, which runs several statements at once.
+		_, err = pool.Exec(ctx, up)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Piccio-Code/MealStore/internal/data"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	MaxAttempts = 8
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	lease       = 2 * time.Minute
+	batchSize   = 20
+)
+
+// Sign returns the signature sent in X-MealStore-Signature: the hex
+// HMAC-SHA256, keyed with the subscription secret, of the timestamp, a dot
+// and the raw body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait before the attempt following the given number of
+// failed ones: 30s, 1m, 2m... capped at six hours.
+func Backoff(failed int) time.Duration {
+	wait := baseBackoff << uint(max(failed-1, 0))
+
+	if wait <= 0 || wait > maxBackoff {
+		return maxBackoff
+	}
+
+	return wait
+}
+
+// Dispatcher sends the queued deliveries stored in webhook_deliveries.
+type Dispatcher struct {
+	webhooks data.WebhookModel
+	client   *http.Client
+	infoLog  *log.Logger
+	errorLog *log.Logger
+}
+
+func NewDispatcher(webhooks data.WebhookModel, infoLog, errorLog *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		infoLog:  infoLog,
+		errorLog: errorLog,
+	}
+}
+
+// Start polls the queue until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.dispatch(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	deliveries, err := d.webhooks.Claim(ctx, batchSize, lease)
+
+	if err != nil {
+		d.errorLog.Println(err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		attempt := d.send(ctx, delivery)
+
+		status := data.DeliveryDelivered
+		next := time.Now()
+
+		if attempt.Error != nil {
+			status = data.DeliveryPending
+			next = next.Add(Backoff(delivery.Attempts + 1))
+
+			if delivery.Attempts+1 >= MaxAttempts {
+				status = data.DeliveryFailed
+			}
+
+			d.errorLog.Printf("webhook delivery %d: %s", delivery.Id, *attempt.Error)
+		}
+
+		err := d.webhooks.RecordAttempt(ctx, delivery.Id, attempt, status, next)
+
+		if err != nil {
+			d.errorLog.Println(err)
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery data.Delivery) (attempt data.DeliveryAttempt) {
+	attempt.AttemptedAt = time.Now()
+
+	fail := func(err error) data.DeliveryAttempt {
+		message := err.Error()
+		attempt.Error = &message
+		attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+
+	if err != nil {
+		return fail(err)
+	}
+
+	timestamp := attempt.AttemptedAt.Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MealStore-Webhooks/1.0")
+	req.Header.Set("X-MealStore-Event", delivery.Event)
+	req.Header.Set("X-MealStore-Delivery", strconv.Itoa(delivery.Id))
+	req.Header.Set("X-MealStore-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-MealStore-Signature", Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	res, err := d.client.Do(req)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	attempt.StatusCode = &res.StatusCode
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fail(fmt.Errorf("unexpected status %d", res.StatusCode))
+	}
+
+	attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+
+	return attempt
+}
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE recipes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    servings INT NOT NULL DEFAULT 1,
+    instructions TEXT NOT NULL DEFAULT '',
+    store_id INT NOT NULL,
+    version uuid NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMP,
+
+    CONSTRAINT recipes_store_id_fk
+        FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
+    CONSTRAINT recipes_name_store_id_unique UNIQUE (store_id, name)
+);
+
+CREATE TABLE recipe_ingredients (
+    id SERIAL PRIMARY KEY,
+    recipe_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+
+    CONSTRAINT recipe_ingredients_recipe_id_fk
+        FOREIGN KEY (recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
+    CONSTRAINT recipe_ingredients_item_id_fk
+        FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+    CONSTRAINT recipe_ingredients_recipe_item_unique UNIQUE (recipe_id, item_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS recipe_ingredients;
+DROP TABLE IF EXISTS recipes;
+-- +goose StatementEnd
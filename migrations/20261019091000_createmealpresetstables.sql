@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meal_presets (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    store_id INT NOT NULL,
+    version uuid NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMP,
+
+    CONSTRAINT meal_presets_store_id_fk
+        FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
+    CONSTRAINT meal_presets_name_store_id_unique UNIQUE (store_id, name)
+);
+
+-- item_id has no foreign key on purpose: entries outlive deleted items so
+-- that logging the preset can report what is gone.
+CREATE TABLE meal_preset_entries (
+    id SERIAL PRIMARY KEY,
+    preset_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+
+    CONSTRAINT meal_preset_entries_preset_id_fk
+        FOREIGN KEY (preset_id) REFERENCES meal_presets(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meal_preset_entries;
+DROP TABLE IF EXISTS meal_presets;
+-- +goose StatementEnd
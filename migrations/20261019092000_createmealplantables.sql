@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE meal_plan_slots (
+    id SERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    plan_date DATE NOT NULL,
+    meal_type VARCHAR(20) NOT NULL,
+    dish_name VARCHAR(255) NOT NULL,
+    version uuid NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMP,
+
+    CONSTRAINT meal_plan_slots_meal_type_check
+        CHECK (meal_type IN ('breakfast', 'lunch', 'dinner', 'snack')),
+    CONSTRAINT meal_plan_slots_user_date_meal_unique UNIQUE (user_id, plan_date, meal_type)
+);
+
+CREATE TABLE meal_plan_items (
+    id SERIAL PRIMARY KEY,
+    slot_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+
+    CONSTRAINT meal_plan_items_slot_id_fk
+        FOREIGN KEY (slot_id) REFERENCES meal_plan_slots(id) ON DELETE CASCADE,
+    CONSTRAINT meal_plan_items_item_id_fk
+        FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS meal_plan_items;
+DROP TABLE IF EXISTS meal_plan_slots;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE shopping_lists (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    store_id INT NOT NULL,
+    version uuid NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    modified_at TIMESTAMP,
+
+    CONSTRAINT shopping_lists_store_id_fk
+        FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE
+);
+
+CREATE TABLE shopping_list_entries (
+    id SERIAL PRIMARY KEY,
+    list_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+    checked BOOLEAN NOT NULL DEFAULT FALSE,
+    checked_at TIMESTAMP,
+
+    CONSTRAINT shopping_list_entries_list_id_fk
+        FOREIGN KEY (list_id) REFERENCES shopping_lists(id) ON DELETE CASCADE,
+    CONSTRAINT shopping_list_entries_item_id_fk
+        FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shopping_list_entries;
+DROP TABLE IF EXISTS shopping_lists;
+-- +goose StatementEnd
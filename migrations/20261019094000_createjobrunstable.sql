@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE job_runs (
+    id SERIAL PRIMARY KEY,
+    job_name VARCHAR(255) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    scheduled_for TIMESTAMP,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    error TEXT,
+
+    CONSTRAINT job_runs_trigger_check
+        CHECK (trigger IN ('schedule', 'manual')),
+    CONSTRAINT job_runs_status_check
+        CHECK (status IN ('running', 'succeeded', 'failed')),
+    CONSTRAINT job_runs_job_name_scheduled_for_unique UNIQUE (job_name, scheduled_for)
+);
+
+CREATE INDEX job_runs_job_name_started_at_idx ON job_runs (job_name, started_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_runs;
+-- +goose StatementEnd
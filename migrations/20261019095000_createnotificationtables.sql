@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notification_preferences (
+    user_id VARCHAR(255) PRIMARY KEY,
+    chat_id VARCHAR(255) NOT NULL,
+    low_stock BOOLEAN NOT NULL DEFAULT TRUE,
+    digest BOOLEAN NOT NULL DEFAULT TRUE,
+    repeat_after_hours INT NOT NULL DEFAULT 0,
+    modified_at TIMESTAMP
+);
+
+CREATE TABLE low_stock_alerts (
+    item_id INT PRIMARY KEY,
+    alerted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT low_stock_alerts_item_id_fk
+        FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS low_stock_alerts;
+DROP TABLE IF EXISTS notification_preferences;
+-- +goose StatementEnd
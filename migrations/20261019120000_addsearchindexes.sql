@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only STABLE because its dictionary can change, which keeps
+-- it out of indexes. Naming the dictionary makes the result fixed.
+CREATE OR REPLACE FUNCTION search_name(text) RETURNS text AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary, $1))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE INDEX items_name_trgm_idx ON items USING gin (search_name(name) gin_trgm_ops);
+CREATE INDEX stores_name_trgm_idx ON stores USING gin (search_name(name) gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS stores_name_trgm_idx;
+DROP INDEX IF EXISTS items_name_trgm_idx;
+DROP FUNCTION IF EXISTS search_name(text);
+DROP EXTENSION IF EXISTS unaccent;
+DROP EXTENSION IF EXISTS pg_trgm;
+-- +goose StatementEnd
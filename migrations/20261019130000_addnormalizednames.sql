@@ -0,0 +1,76 @@
+-- +goose Up
+-- +goose StatementBegin
+-- normalize_name is what makes two names the same: surrounding spaces are
+-- dropped, inner runs of spaces collapsed, the text is NFKC normalized and
+-- case folded.
+CREATE OR REPLACE FUNCTION normalize_name(text) RETURNS text AS $$
+    SELECT lower(normalize(regexp_replace(btrim($1), '\s+', ' ', 'g'), NFKC))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+-- Rows that already collide would make the unique constraints fail with a
+-- bare duplicate key error. List them all instead so they can be merged or
+-- renamed before running the migration again.
+DO $$
+DECLARE
+    item_collisions text;
+    store_collisions text;
+BEGIN
+    SELECT string_agg(format('store %s: %s', store_id, names), E'\n')
+    INTO item_collisions
+    FROM (
+        SELECT store_id, string_agg(format('%s (id %s)', quote_literal(name), id), ', ' ORDER BY id) AS names
+        FROM items
+        GROUP BY store_id, normalize_name(name)
+        HAVING count(*) > 1
+    ) c;
+
+    SELECT string_agg(format('user %s: %s', user_id, names), E'\n')
+    INTO store_collisions
+    FROM (
+        SELECT user_id, string_agg(format('%s (id %s)', quote_literal(name), id), ', ' ORDER BY id) AS names
+        FROM stores
+        GROUP BY user_id, normalize_name(name)
+        HAVING count(*) > 1
+    ) c;
+
+    IF item_collisions IS NOT NULL OR store_collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'names that differ only in case, spacing or Unicode form must be merged or renamed first'
+            USING DETAIL = concat_ws(E'\n', 'items:' || E'\n' || item_collisions, 'stores:' || E'\n' || store_collisions);
+    END IF;
+END $$;
+
+ALTER TABLE items
+    ADD COLUMN name_norm TEXT GENERATED ALWAYS AS (normalize_name(name)) STORED;
+
+ALTER TABLE stores
+    ADD COLUMN name_norm TEXT GENERATED ALWAYS AS (normalize_name(name)) STORED;
+
+ALTER TABLE items
+    DROP CONSTRAINT name_store_id_unique,
+    ADD CONSTRAINT items_store_id_name_norm_unique UNIQUE (store_id, name_norm);
+
+ALTER TABLE stores
+    DROP CONSTRAINT name_user_unique,
+    DROP CONSTRAINT IF EXISTS stores_user_id_name_unique,
+    ADD CONSTRAINT stores_user_id_name_norm_unique UNIQUE (user_id, name_norm);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE stores
+    DROP CONSTRAINT stores_user_id_name_norm_unique,
+    ADD CONSTRAINT name_user_unique UNIQUE (user_id, name),
+    ADD CONSTRAINT stores_user_id_name_unique UNIQUE (user_id, name);
+
+ALTER TABLE items
+    DROP CONSTRAINT items_store_id_name_norm_unique,
+    ADD CONSTRAINT name_store_id_unique UNIQUE (store_id, name);
+
+ALTER TABLE stores
+    DROP COLUMN name_norm;
+
+ALTER TABLE items
+    DROP COLUMN name_norm;
+
+DROP FUNCTION IF EXISTS normalize_name(text);
+-- +goose StatementEnd
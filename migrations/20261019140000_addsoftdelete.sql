@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE stores
+    ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE items
+    ADD COLUMN deleted_at TIMESTAMP;
+
+-- Names only have to be unique among the rows that are not in the trash;
+-- a clash is checked again when a row is restored.
+ALTER TABLE items
+    DROP CONSTRAINT items_store_id_name_norm_unique;
+
+ALTER TABLE stores
+    DROP CONSTRAINT stores_user_id_name_norm_unique;
+
+CREATE UNIQUE INDEX items_store_id_name_norm_unique ON items (store_id, name_norm) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX stores_user_id_name_norm_unique ON stores (user_id, name_norm) WHERE deleted_at IS NULL;
+
+CREATE INDEX items_deleted_at_idx ON items (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX stores_deleted_at_idx ON stores (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM items WHERE deleted_at IS NOT NULL;
+DELETE FROM stores WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS stores_deleted_at_idx;
+DROP INDEX IF EXISTS items_deleted_at_idx;
+DROP INDEX IF EXISTS stores_user_id_name_norm_unique;
+DROP INDEX IF EXISTS items_store_id_name_norm_unique;
+
+ALTER TABLE stores
+    ADD CONSTRAINT stores_user_id_name_norm_unique UNIQUE (user_id, name_norm);
+
+ALTER TABLE items
+    ADD CONSTRAINT items_store_id_name_norm_unique UNIQUE (store_id, name_norm);
+
+ALTER TABLE items
+    DROP COLUMN deleted_at;
+
+ALTER TABLE stores
+    DROP COLUMN deleted_at;
+-- +goose StatementEnd